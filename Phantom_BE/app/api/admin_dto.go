@@ -0,0 +1,125 @@
+package api
+
+import (
+	"PhantomBE/app/schedule"
+	"PhantomBE/global"
+)
+
+// 1. Request structure for creating a pharmacy
+type CreatePharmacyRequest struct {
+	Name                 string               `json:"name" binding:"required,max=255" validate_msg:"Name is required and cannot exceed 255 characters"`
+	CashBalance          float64              `json:"cash_balance" binding:"min=0" validate_msg:"Cash balance cannot be negative"`
+	OpeningHours         *OpeningHoursRequest `json:"opening_hours,omitempty"`
+	Masks                []MaskRequest        `json:"masks,omitempty" binding:"max=1000,dive" validate_msg:"Masks cannot exceed 1000 entries"`
+	CommissionPercent    *float64             `json:"commission_percent,omitempty" binding:"omitempty,min=0,max=100" validate_msg:"Commission percent must be between 0 and 100"`
+	CommissionFixedFee   *float64             `json:"commission_fixed_fee,omitempty" binding:"omitempty,min=0" validate_msg:"Commission fixed fee cannot be negative"`
+	AcceptsOrdersAnytime bool                 `json:"accepts_orders_anytime"`
+	ObservesHolidays     bool                 `json:"observes_holidays"`
+	Address              string               `json:"address,omitempty" binding:"max=500" validate_msg:"Address cannot exceed 500 characters"`
+	Latitude             *float64             `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90" validate_msg:"Latitude must be between -90 and 90 and given together with longitude"`
+	Longitude            *float64             `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180" validate_msg:"Longitude must be between -180 and 180"`
+}
+
+// 2. Request structure for updating a pharmacy, omitted fields are left unchanged
+type UpdatePharmacyRequest struct {
+	Name                 *string  `json:"name,omitempty" binding:"omitempty,min=1,max=255" validate_msg:"Name must be 1-255 characters"`
+	CashBalance          *float64 `json:"cash_balance,omitempty" binding:"omitempty,min=0" validate_msg:"Cash balance cannot be negative"`
+	CommissionPercent    *float64 `json:"commission_percent,omitempty" binding:"omitempty,min=0,max=100" validate_msg:"Commission percent must be between 0 and 100"`
+	CommissionFixedFee   *float64 `json:"commission_fixed_fee,omitempty" binding:"omitempty,min=0" validate_msg:"Commission fixed fee cannot be negative"`
+	AcceptsOrdersAnytime *bool    `json:"accepts_orders_anytime,omitempty"`
+	ObservesHolidays     *bool    `json:"observes_holidays,omitempty"`
+	Address              *string  `json:"address,omitempty" binding:"omitempty,max=500" validate_msg:"Address cannot exceed 500 characters"`
+	Latitude             *float64 `json:"latitude,omitempty" binding:"omitempty,min=-90,max=90" validate_msg:"Latitude must be between -90 and 90 and given together with longitude"`
+	Longitude            *float64 `json:"longitude,omitempty" binding:"omitempty,min=-180,max=180" validate_msg:"Longitude must be between -180 and 180"`
+}
+
+// 3. Request structure for adding a mask to a pharmacy
+type MaskRequest struct {
+	Name  string  `json:"name" binding:"required,max=255" validate_msg:"Mask name is required and cannot exceed 255 characters"`
+	Price float64 `json:"price" binding:"required,gt=0" validate_msg:"Mask price is required and must be greater than 0"`
+}
+
+// 4. Request structure for updating a mask, omitted fields are left unchanged
+type UpdateMaskRequest struct {
+	Name  *string  `json:"name,omitempty" binding:"omitempty,min=1,max=255" validate_msg:"Mask name must be 1-255 characters"`
+	Price *float64 `json:"price,omitempty" binding:"omitempty,gt=0" validate_msg:"Mask price must be greater than 0"`
+}
+
+// 5. Request structure for replacing the weekly opening hours of a pharmacy,
+// given either as structured entries or in the raw format of the pharmacy data
+type OpeningHoursRequest struct {
+	Entries []OpeningHourRequest `json:"entries,omitempty" binding:"max=50,dive" validate_msg:"Give either entries (at most 50) or raw opening hours, not both"`
+	Raw     string               `json:"raw,omitempty" binding:"omitempty,max=500,opening_hours_format" validate_msg:"Raw opening hours must look like 'Mon - Fri 08:00 - 17:00 / Sat 09:00 - 12:00'"`
+}
+
+type OpeningHourRequest struct {
+	Day   string `json:"day" binding:"required,valid_day" validate_msg:"Day must be a day of the week such as Monday, Mon or 星期一"`
+	Open  string `json:"open" binding:"required,time_format" validate_msg:"Open must be a time of day such as 08:00, 08.00 or 8am"`
+	Close string `json:"close" binding:"required,close_time_format" validate_msg:"Close must be a time of day such as 17:00, 17.00 or 5pm, or 24:00 for the end of the day"`
+}
+
+// 6. Request structure for overriding the hours of a pharmacy on one date,
+// either closed all day or open for the given shifts instead
+type OpeningOverrideRequest struct {
+	Closed bool           `json:"closed"`
+	Shifts []ShiftRequest `json:"shifts,omitempty" binding:"max=10,dive" validate_msg:"Give either closed or shifts (at most 10), not both"`
+	Reason string         `json:"reason,omitempty" binding:"max=255" validate_msg:"Reason cannot exceed 255 characters"`
+}
+
+type ShiftRequest struct {
+	Open  string `json:"open" binding:"required,time_format" validate_msg:"Open must be a time of day such as 08:00, 08.00 or 8am"`
+	Close string `json:"close" binding:"required,close_time_format" validate_msg:"Close must be a time of day such as 17:00, 17.00 or 5pm, or 24:00 for the end of the day"`
+}
+
+// 7. Query structure for listing the overrides of a pharmacy
+type OpeningOverridesRequest struct {
+	From string `form:"from" binding:"omitempty,date_format" validate_msg:"From must be in YYYY-MM-DD format"`
+	To   string `form:"to" binding:"omitempty,date_format" validate_msg:"To must be in YYYY-MM-DD format"`
+}
+
+// 8. Request structure for adding a date to the national holiday calendar
+type HolidayRequest struct {
+	Name string `json:"name" binding:"required,max=255" validate_msg:"Name is required and cannot exceed 255 characters"`
+}
+
+// 9. Query structure for listing holidays
+type HolidaysRequest struct {
+	Year int `form:"year" binding:"omitempty,min=1900,max=9999" validate_msg:"Year must be a four-digit year"`
+}
+
+// Response structure
+
+// 5. Opening Hours Response
+type OpeningHoursResponse struct {
+	PharmacyID   uint                 `json:"pharmacy_id"`
+	PharmacyName string               `json:"pharmacy_name"`
+	OpeningHours []global.OpeningHour `json:"opening_hours"`
+	Changes      []schedule.Change    `json:"changes,omitempty"` // made normalizing the hours
+}
+
+// 6. Opening Override Response
+type OpeningOverrideResponse struct {
+	Override global.OpeningOverride `json:"override"`
+	Changes  []schedule.Change      `json:"changes,omitempty"` // made normalizing the shifts
+}
+
+// 7. Opening Overrides Response
+type OpeningOverridesResponse struct {
+	PharmacyID   uint                     `json:"pharmacy_id"`
+	PharmacyName string                   `json:"pharmacy_name"`
+	Overrides    []global.OpeningOverride `json:"overrides"`
+	Count        int                      `json:"count"`
+}
+
+// 8. Holidays Response
+type HolidaysResponse struct {
+	Holidays []global.Holiday `json:"holidays"`
+	Count    int              `json:"count"`
+}
+
+// Delete Response
+type DeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	ID      uint   `json:"id"`
+}
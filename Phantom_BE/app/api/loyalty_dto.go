@@ -0,0 +1,31 @@
+package api
+
+import (
+	"PhantomBE/global"
+	"time"
+)
+
+// 1. Request structure for a user's loyalty points balance
+type PointsBalanceRequest struct {
+	UserID uint `json:"user_id" binding:"required,positive_uint" validate_msg:"User ID must be a positive number"`
+	Limit  int  `json:"limit" binding:"non_negative_int,max=500" validate_msg:"Limit must be between 0 and 500"`
+}
+
+// Response structure
+
+// 1. Points Balance Response
+type PointsBalanceResponse struct {
+	UserID         uint                       `json:"user_id"`
+	UserName       string                     `json:"user_name"`
+	Points         int                        `json:"points"`
+	Value          float64                    `json:"value"` // dollar value if redeemed
+	ExpiredNow     int                        `json:"expired_now"`
+	NextExpiry     *PointsExpiry              `json:"next_expiry,omitempty"`
+	Ledger         []global.LoyaltyPointEntry `json:"ledger"`
+	LedgerCount    int                        `json:"ledger_count"`
+}
+
+type PointsExpiry struct {
+	Points    int       `json:"points"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -2,30 +2,90 @@ package api
 
 import(
 	"PhantomBE/global"
+	"PhantomBE/app/suggest"
 	"time"
 )
 
+// Pagination parameters shared by list requests
+type PageRequest struct {
+	PageSize int    `json:"page_size,omitempty" form:"page_size" binding:"omitempty,min=1,max=1000" validate_msg:"Page size must be between 1 and 1000"`
+	Cursor   string `json:"cursor,omitempty" form:"cursor" binding:"max=512" validate_msg:"Cursor must be the next_cursor of a previous page"`
+}
+
+// Associations and columns of the pharmacies in a response, shared by pharmacy
+// requests, e.g. include=masks,opening_hours&fields=name,cash_balance
+type PharmacyFields struct {
+	Include string `json:"include,omitempty" form:"include" binding:"omitempty,max=64,pharmacy_include" validate_msg:"Include must be a comma-separated list of masks and opening_hours"`
+	Fields  string `json:"fields,omitempty" form:"fields" binding:"omitempty,max=256,pharmacy_fields" validate_msg:"Fields must be a comma-separated list of id, name, cash_balance, commission_percent, commission_fixed_fee, accepts_orders_anytime, observes_holidays, address, latitude and longitude"`
+}
+
+// PharmacyFieldKeys maps the pharmacy columns clients may select to their JSON keys
+var PharmacyFieldKeys = map[string]string{
+	"id":                     "ID",
+	"name":                   "name",
+	"cash_balance":           "cashBalance",
+	"commission_percent":     "commissionPercent",
+	"commission_fixed_fee":   "commissionFixedFee",
+	"accepts_orders_anytime": "acceptsOrdersAnytime",
+	"observes_holidays":      "observesHolidays",
+	"address":                "address",
+	"latitude":               "latitude",
+	"longitude":              "longitude",
+}
+
+// PharmacyIncludeKeys maps the pharmacy associations clients may include to their JSON keys
+var PharmacyIncludeKeys = map[string]string{
+	"masks":         "masks",
+	"opening_hours": "openingHours",
+}
+
 // 1. Request structure for open pharmacies query
 type OpenPharmaciesRequest struct {
-    Day  string `json:"day" binding:"required,valid_day" validate_msg:"Day must be a valid day of the week"`
-    Time string `json:"time" binding:"required,time_format" validate_msg:"Time must be in HH:MM format"`
+	PageRequest
+	PharmacyFields
+    Day  string `json:"day" form:"day" binding:"omitempty,valid_day" validate_msg:"Day must be a valid day of the week such as Monday, Mon or 星期一, given with time; or give date and time, now, or from and to"`
+    Time string `json:"time" form:"time" binding:"omitempty,time_format" validate_msg:"Time must be a time of day such as 14:30, 14.30 or 2pm"`
+    Date string `json:"date,omitempty" form:"date" binding:"omitempty,date_format" validate_msg:"Date must be in YYYY-MM-DD format"` // instead of day, honors overrides and holidays
+    Now  bool   `json:"now" form:"now"` // use the server clock in the business time zone instead of day/time
+    From string `json:"from,omitempty" form:"from" binding:"omitempty,datetime_format" validate_msg:"From must be in YYYY-MM-DD HH:MM format"`
+    To   string `json:"to,omitempty" form:"to" binding:"omitempty,datetime_format" validate_msg:"To must be in YYYY-MM-DD HH:MM format, after from and at most 7 days later"`
+    Mode string `json:"mode,omitempty" form:"mode" binding:"omitempty,oneof=entire any" validate_msg:"Mode must be 'entire' or 'any' and needs from and to"` // interval queries; default entire
 }
 
 // 2. Request structure for pharmacies Masks
 type PharmacyMasksRequest struct {
-	PharmacyID uint   `json:"pharmacy_id" binding:"required,positive_uint" validate_msg:"Pharmacy ID is required and must be greater than 0"`
-    Sort       string `json:"sort,omitempty" binding:"omitempty,valid_sort" validate_msg:"Time must be name or price"`
-    Order      string `json:"order,omitempty" binding:"omitempty,valid_order" validate_msg:"Time must be asc or desc"`
+	PageRequest
+	PharmacyID uint   `json:"pharmacy_id" form:"-" binding:"required,positive_uint" validate_msg:"Pharmacy ID is required and must be greater than 0"` // path parameter in v2
+	MaskAttributeFilter
+    Sort       string `json:"sort,omitempty" form:"sort" binding:"omitempty,max=128,valid_sort" validate_msg:"Sort must be a comma-separated list of name, price, brand, color, pack_size, price_per_unit or popularity, each optionally followed by asc or desc, e.g. 'price desc, name'"` // multi-key, e.g. "price desc, name" or "-price,name"
+    Order      string `json:"order,omitempty" form:"order" binding:"omitempty,valid_order" validate_msg:"Order must be asc or desc"` // default direction of the sort keys
+}
+
+// MaskSortKeys are the keys mask lists may be sorted by
+var MaskSortKeys = []string{"name", "price", "brand", "color", "pack_size", "price_per_unit", "popularity"}
+
+// Optional mask attribute conditions shared by the mask list and pharmacy filter
+type MaskAttributeFilter struct {
+	Brand           string  `json:"brand,omitempty" form:"brand" binding:"omitempty,max=100" validate_msg:"Brand cannot exceed 100 characters"`
+	Color           string  `json:"color,omitempty" form:"color" binding:"omitempty,max=50" validate_msg:"Color cannot exceed 50 characters"`
+	PackSize        int     `json:"pack_size,omitempty" form:"pack_size" binding:"omitempty,min=1" validate_msg:"Pack size must be at least 1"`
+	MinPricePerUnit float64 `json:"min_price_per_unit,omitempty" form:"min_price_per_unit" binding:"omitempty,non_negative_float" validate_msg:"Min price per unit cannot be negative"`
+	MaxPricePerUnit float64 `json:"max_price_per_unit,omitempty" form:"max_price_per_unit" binding:"omitempty,non_negative_float" validate_msg:"Max price per unit cannot be negative"`
 }
 // 3. Request structure for pharmacies filter
 type PharmacyFilterRequest struct {
-	Operator string  `json:"operator" binding:"required,valid_operator" validate_msg:"Operator is required and must be 'more' or 'less'"`
-    Count    int     `json:"count" binding:"required,non_negative_int" validate_msg:"Count is required and cannot be negative"`
-    MinPrice float64 `json:"min_price" binding:"required,non_negative_float" validate_msg:"Min price is required and cannot be negative or 0.0"`
-    MaxPrice float64 `json:"max_price" binding:"required,non_negative_float" validate_msg:"Max price is required and cannot be negative"`
+	PageRequest
+	PharmacyFields
+	Operator string  `json:"operator" form:"operator" binding:"required,valid_operator" validate_msg:"Operator is required and must be 'more' or 'less'"`
+    Count    int     `json:"count" form:"count" binding:"required,non_negative_int" validate_msg:"Count is required and cannot be negative"`
+    MinPrice float64 `json:"min_price" form:"min_price" binding:"required,non_negative_float" validate_msg:"Min price is required and cannot be negative or 0.0"`
+    MaxPrice float64 `json:"max_price" form:"max_price" binding:"required,non_negative_float" validate_msg:"Max price is required and cannot be negative"`
+    PriceBasis string `json:"price_basis,omitempty" form:"price_basis" binding:"omitempty,valid_price_basis" validate_msg:"Price basis must be 'base' or 'best_tier'"`
+	MaskAttributeFilter
 }
 // 4. Request structure for finding top users
 type TopUsersRequest struct {
+	PageRequest
     StartDate string `json:"start_date" binding:"required,date_format" validate_msg:"Start date must be in YYYY-MM-DD format"`
     EndDate   string `json:"end_date" binding:"required,date_format" validate_msg:"End date must be in YYYY-MM-DD format and must be after start date"`
     Limit     int    `json:"limit" binding:"non_negative_int" validate_msg:"Limit must be a positive number or zero"`
@@ -38,8 +98,24 @@ type TransactionSummaryRequest struct {
 
 // 6. Request structure for search
 type SearchRequest struct {
+	PageRequest
 	Query string `json:"query" binding:"required,min_search_length,max_search_length,safe_search" validate_msg:"Query must be 2-100 characters and contain only letters, numbers, spaces, hyphens, apostrophes, and periods"`
 	Type  string `json:"type" binding:"omitempty,search_type" validate_msg:"Type must be 'pharmacy', 'mask', 'user', or 'all'"`
+	// Filters of one result type, the other types are not affected
+	Mask     *MaskSearchFilter     `json:"mask,omitempty"`
+	Pharmacy *PharmacySearchFilter `json:"pharmacy,omitempty"`
+}
+// Filters of the mask results of a search
+type MaskSearchFilter struct {
+	MaskAttributeFilter
+	MinPrice   float64 `json:"min_price,omitempty" binding:"omitempty,non_negative_float" validate_msg:"Min price cannot be negative"`
+	MaxPrice   float64 `json:"max_price,omitempty" binding:"omitempty,non_negative_float,gtefield=MinPrice" validate_msg:"Max price cannot be negative or less than min price"`
+	PharmacyID uint    `json:"pharmacy_id,omitempty" binding:"omitempty,positive_uint" validate_msg:"Pharmacy ID must be greater than 0"`
+}
+// Filters of the pharmacy results of a search
+type PharmacySearchFilter struct {
+	MinCashBalance *float64 `json:"min_cash_balance,omitempty" validate_msg:"Min cash balance must be a number"`
+	MaxCashBalance *float64 `json:"max_cash_balance,omitempty" validate_msg:"Max cash balance must be a number"`
 }
 
 // 7. Request structure for purchase transaction
@@ -48,14 +124,117 @@ type PurchaseRequest struct {
     PharmacyID uint `json:"pharmacy_id" binding:"required,positive_uint" validate_msg:"Pharmacy ID must be a positive number"`
     MaskID     uint `json:"mask_id" binding:"required,positive_uint" validate_msg:"Mask ID must be a positive number"`
     Quantity   int  `json:"quantity" binding:"required,positive_int,max_quantity" validate_msg:"Quantity must be between 1 and 1000"`
+    CouponCode string `json:"coupon_code,omitempty" binding:"omitempty,coupon_code" validate_msg:"Coupon code must be 3-32 letters, digits, hyphens or underscores"`
+    RedeemPoints int  `json:"redeem_points,omitempty" binding:"non_negative_int" validate_msg:"Redeem points cannot be negative"`
+}
+
+// 9. Request structure for commission summary
+type CommissionSummaryRequest struct {
+	StartDate string `json:"start_date" binding:"required,date_format" validate_msg:"Start date must be in YYYY-MM-DD format"`
+	EndDate   string `json:"end_date" binding:"required,date_format" validate_msg:"End date must be in YYYY-MM-DD format and must be after start date"`
+}
+
+// 10. Request structure for replacing a mask's price tiers
+type MaskPriceTiersRequest struct {
+	MaskID uint               `json:"mask_id" binding:"required,positive_uint" validate_msg:"Mask ID is required and must be greater than 0"`
+	Tiers  []PriceTierRequest `json:"tiers" binding:"max=10,dive" validate_msg:"Tiers must be at most 10 entries with distinct min quantities"`
+}
+
+type PriceTierRequest struct {
+	MinQuantity     int     `json:"min_quantity" binding:"required,positive_int,max_quantity" validate_msg:"Min quantity must be between 1 and 1000"`
+	DiscountPercent float64 `json:"discount_percent" binding:"required,gt=0,lt=100" validate_msg:"Discount percent must be greater than 0 and less than 100"`
+}
+
+// 11. Request structure for refunding purchases
+type RefundRequest struct {
+	UserID      uint   `json:"user_id" binding:"required,positive_uint" validate_msg:"User ID must be a positive number"`
+	PurchaseIDs []uint `json:"purchase_ids" binding:"required,min=1,max=1000,dive,positive_uint" validate_msg:"Purchase IDs must be a list of 1 to 1000 positive numbers"`
+}
+
+// 12. Request structure for toggling round-the-clock online orders
+type OrderAvailabilityRequest struct {
+	PharmacyID           uint  `json:"pharmacy_id" binding:"required,positive_uint" validate_msg:"Pharmacy ID is required and must be greater than 0"`
+	AcceptsOrdersAnytime *bool `json:"accepts_orders_anytime" binding:"required" validate_msg:"Accepts orders anytime is required"`
+}
+
+// 13. Request structure for pharmacies opening soon
+type OpeningSoonRequest struct {
+	Minutes int `json:"minutes" form:"minutes" binding:"required,min=1,max=1440" validate_msg:"Minutes is required and must be between 1 and 1440"`
+	PharmacyFields
+}
+
+// 14. Request structure for comparing the prices of a product across pharmacies
+type PriceComparisonRequest struct {
+	ProductID uint   `json:"product_id,omitempty" form:"product_id" binding:"omitempty,positive_uint" validate_msg:"Either product_id (greater than 0) or name is required, not both"`
+	Name      string `json:"name,omitempty" form:"name" binding:"omitempty,max=200" validate_msg:"Name cannot exceed 200 characters"`
+	Sort      string `json:"sort,omitempty" form:"sort" binding:"omitempty,max=128,valid_sort" validate_msg:"Sort must be a comma-separated list of name, price, brand, color, pack_size, price_per_unit or popularity, each optionally followed by asc or desc, e.g. 'price desc, name'"` // default price
+}
+
+// 15. Request structure for the composite pharmacy filter. Every criterion is
+// optional and pharmacies must match all that are given.
+type CompositeFilterRequest struct {
+	PageRequest
+	PharmacyFields
+	// Open on a day of the week at a time
+	Day  string `json:"day,omitempty" form:"day" binding:"omitempty,valid_day" validate_msg:"Day must be a valid day of the week and given together with time"`
+	Time string `json:"time,omitempty" form:"time" binding:"omitempty,time_format" validate_msg:"Time must be a time of day such as 14:30, 14.30 or 2pm"`
+	// Masks that count toward the mask count
+	MaskAttributeFilter
+	MinPrice   float64 `json:"min_price,omitempty" form:"min_price" binding:"omitempty,non_negative_float" validate_msg:"Min price cannot be negative"`
+	MaxPrice   float64 `json:"max_price,omitempty" form:"max_price" binding:"omitempty,non_negative_float" validate_msg:"Max price cannot be negative or less than min price"`
+	PriceBasis string  `json:"price_basis,omitempty" form:"price_basis" binding:"omitempty,valid_price_basis" validate_msg:"Price basis must be 'base' or 'best_tier'"`
+	// Number of matching masks: more, less or equal to count, or between count and count_max inclusive
+	CountOperator string `json:"count_operator,omitempty" form:"count_operator" binding:"omitempty,oneof=more less between equal" validate_msg:"Count operator must be more, less, between or equal and given together with count"`
+	Count         *int   `json:"count,omitempty" form:"count" binding:"omitempty,min=0" validate_msg:"Count cannot be negative"`
+	CountMax      *int   `json:"count_max,omitempty" form:"count_max" binding:"omitempty,min=0" validate_msg:"Count max is required by between only and cannot be less than count"`
+	// Cash balance range, either end optional
+	MinCashBalance *float64 `json:"min_cash_balance,omitempty" form:"min_cash_balance" binding:"omitempty" validate_msg:"Min cash balance must be a number"`
+	MaxCashBalance *float64 `json:"max_cash_balance,omitempty" form:"max_cash_balance" binding:"omitempty" validate_msg:"Max cash balance cannot be less than min cash balance"`
+}
+
+// 16. Request structure for type-ahead suggestions
+type SuggestRequest struct {
+	Query string `json:"q" form:"q" binding:"required,max_search_length,safe_search" validate_msg:"Query is required, up to 100 characters of letters, numbers, spaces, hyphens, apostrophes, and periods"`
+	Type  string `json:"type,omitempty" form:"type" binding:"omitempty,oneof=pharmacy mask brand all" validate_msg:"Type must be 'pharmacy', 'mask', 'brand', or 'all'"`
+	Limit int    `json:"limit,omitempty" form:"limit" binding:"omitempty,min=1,max=20" validate_msg:"Limit must be between 1 and 20"`
+}
+
+// 17. Request structure for pharmacies near a point
+type NearbyPharmaciesRequest struct {
+	PageRequest
+	PharmacyFields
+	Latitude  *float64 `json:"lat" form:"lat" binding:"required,min=-90,max=90" validate_msg:"Latitude is required and must be between -90 and 90"`
+	Longitude *float64 `json:"lng" form:"lng" binding:"required,min=-180,max=180" validate_msg:"Longitude is required and must be between -180 and 180"`
+	RadiusKm  float64  `json:"radius_km,omitempty" form:"radius_km" binding:"omitempty,gt=0,max=100" validate_msg:"Radius must be greater than 0 and at most 100 km"`
+	OpenNow   bool     `json:"open_now,omitempty" form:"open_now"`
+	// Sells the product, by ID or by mask name
+	ProductID uint   `json:"product_id,omitempty" form:"product_id" binding:"omitempty,positive_uint" validate_msg:"Product ID must be greater than 0"`
+	Mask      string `json:"mask,omitempty" form:"mask" binding:"omitempty,max=255,excluded_with=ProductID" validate_msg:"Mask name cannot exceed 255 characters or be combined with product_id"`
 }
 
 // Response structure
 
+// Pagination state returned by list responses
+type PageInfo struct {
+	PageSize   int    `json:"page_size"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
 // 1. Open Pharmacies Response
 type OpenPharmaciesResponse struct {
-	Pharmacies []global.Pharmacy   `json:"pharmacies"`
-	Count      int                 `json:"count"`
+	At         time.Time              `json:"at"` // moment the pharmacies were checked against
+	Pharmacies []PharmacyAvailability `json:"pharmacies"`
+	Count      int                    `json:"count"`
+	PageInfo
+}
+
+// Pharmacy with when it closes (if open) or next opens (if closed)
+type PharmacyAvailability struct {
+	global.Pharmacy
+	OpenNow    bool       `json:"open_now"`
+	ClosesAt   *time.Time `json:"closes_at,omitempty"`
+	NextOpenAt *time.Time `json:"next_open_at,omitempty"`
 }
 
 type OpenPharmacy struct{
@@ -68,6 +247,7 @@ type PharmacyMasksResponse struct {
 	PharmacyName string         `json:"pharmacy_name"`
 	Masks        []global.Mask  `json:"masks"`
 	Count        int            `json:"count"`
+	PageInfo
 }
 // 3. Pharmacy Filter Response
 type PharmacyWithCount struct {
@@ -77,6 +257,7 @@ type PharmacyWithCount struct {
 type PharmacyFilterResponse struct{
 	Pharmacies []PharmacyWithCount  `json:"pharmacies"`
 	Count      int                  `json:"count"`
+	PageInfo
 }
 
 // 4. Top Users Response
@@ -84,6 +265,7 @@ type TopUsersResponse struct {
 	TopUsers []UserTransactionSummary `json:"top_users"`
 	Count    int                      `json:"count"`
 	Limit    int                      `json:"limit"`
+	PageInfo
 }
 
 type UserTransactionSummary struct {
@@ -115,15 +297,44 @@ type SearchResponse struct {
 	Count   int            `json:"count"`
 	Query   string         `json:"query"`
 	Type    string         `json:"type"`
+	Facets  *SearchFacets  `json:"facets,omitempty"` // first page only
+	PageInfo
+}
+
+// Counts of all results of a search; brand, color, price and pharmacy facets count masks
+type SearchFacets struct {
+	Types        map[string]int64 `json:"types"`
+	Brands       []FacetCount     `json:"brands,omitempty"`
+	Colors       []FacetCount     `json:"colors,omitempty"`
+	PriceBuckets []FacetCount     `json:"price_buckets,omitempty"`
+	Pharmacies   []PharmacyFacet  `json:"pharmacies,omitempty"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type PharmacyFacet struct {
+	PharmacyID   uint   `json:"pharmacy_id"`
+	PharmacyName string `json:"pharmacy_name"`
+	Count        int64  `json:"count"`
+}
+
+// A matched part of a result name, in characters from Start up to End
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 type SearchResult struct {
-	Type       string   `json:"type"`
-	ID         uint     `json:"id"`
-	Name       string   `json:"name"`
-	Price      *float64 `json:"price,omitempty"`      // Only for masks
-	PharmacyID *uint    `json:"pharmacy_id,omitempty"` // Only for masks
-	Relevance  float64  `json:"relevance"`
+	Type       string      `json:"type"`
+	ID         uint        `json:"id"`
+	Name       string      `json:"name"`
+	Price      *float64    `json:"price,omitempty"`       // Only for masks
+	PharmacyID *uint       `json:"pharmacy_id,omitempty"` // Only for masks
+	Relevance  float64     `json:"relevance"`
+	Highlights []Highlight `json:"highlights,omitempty"` // empty for typo matches
 }
 
 // 7. Purchase Response
@@ -142,11 +353,138 @@ type PurchaseDetails struct {
 	PharmacyName    string  `json:"pharmacy_name"`
 	MaskID          uint    `json:"mask_id"`
 	MaskName        string  `json:"mask_name"`
-	UnitPrice       float64 `json:"unit_price"`
+	BaseUnitPrice   float64 `json:"base_unit_price"`
+	UnitPrice       float64 `json:"unit_price"` // after any quantity price tier
+	PriceTier       *global.MaskPriceTier `json:"price_tier,omitempty"`
 	Quantity        int     `json:"quantity"`
+	SubtotalAmount  float64 `json:"subtotal_amount"` // before discount
+	CouponCode      string  `json:"coupon_code,omitempty"`
+	DiscountAmount  float64 `json:"discount_amount"`
+	PointsRedeemed  int     `json:"points_redeemed"`
+	PointsDiscount  float64 `json:"points_discount"`
 	TotalAmount     float64 `json:"total_amount"`
+	CommissionPercent  float64 `json:"commission_percent"`
+	CommissionFixedFee float64 `json:"commission_fixed_fee"`
+	CommissionAmount   float64 `json:"commission_amount"`
+	PharmacyAmount     float64 `json:"pharmacy_amount"` // credited to the pharmacy after commission
 	PreviousBalance float64 `json:"previous_balance"`
 	NewBalance      float64 `json:"new_balance"`
+	PointsEarned    int     `json:"points_earned"`
+	PointsBalance   int     `json:"points_balance"`
+}
+
+// 9. Commission Summary Response
+type CommissionSummaryResponse struct {
+	Summary         CommissionSummaryData       `json:"summary"`
+	Pharmacies      []PharmacyCommissionSummary `json:"pharmacies"`
+	PlatformBalance float64                     `json:"platform_balance"`
+}
+
+type CommissionSummaryData struct {
+	GrossAmount      float64 `json:"gross_amount"`
+	CommissionAmount float64 `json:"commission_amount"`
+	PharmacyAmount   float64 `json:"pharmacy_amount"`
+	TransactionCount int64   `json:"transaction_count"`
+}
+
+type PharmacyCommissionSummary struct {
+	PharmacyName     string  `json:"pharmacy_name"`
+	GrossAmount      float64 `json:"gross_amount"`
+	CommissionAmount float64 `json:"commission_amount"`
+	PharmacyAmount   float64 `json:"pharmacy_amount"`
+	TransactionCount int64   `json:"transaction_count"`
+}
+
+// 10. Mask Price Tiers Response
+type MaskPriceTiersResponse struct {
+	MaskID    uint                   `json:"mask_id"`
+	MaskName  string                 `json:"mask_name"`
+	BasePrice float64                `json:"base_price"`
+	Tiers     []global.MaskPriceTier `json:"tiers"`
+}
+
+// 11. Refund Response
+type RefundResponse struct {
+	Success        bool      `json:"success"`
+	Message        string    `json:"message"`
+	RefundedIDs    []uint    `json:"refunded_ids"`
+	RefundAmount   float64   `json:"refund_amount"`
+	PointsRestored int       `json:"points_restored"` // redeemed on the purchases, given back
+	PointsReversed int       `json:"points_reversed"` // earned by the purchases, taken back
+	NewBalance     float64   `json:"new_balance"`
+	PointsBalance  int       `json:"points_balance"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// 12. Order Availability Response
+type OrderAvailabilityResponse struct {
+	PharmacyID           uint   `json:"pharmacy_id"`
+	PharmacyName         string `json:"pharmacy_name"`
+	AcceptsOrdersAnytime bool   `json:"accepts_orders_anytime"`
+}
+
+// 13. Opening Soon Response
+type OpeningSoonResponse struct {
+	At         time.Time              `json:"at"`
+	Minutes    int                    `json:"minutes"`
+	Pharmacies []PharmacyAvailability `json:"pharmacies"`
+	Count      int                    `json:"count"`
+}
+
+// 14. Price Comparison Response
+type PriceComparisonResponse struct {
+	Product global.Product `json:"product"`
+	At      time.Time      `json:"at"`
+	Offers  []MaskOffer    `json:"offers"`
+	Count   int            `json:"count"`
+	Stats   *PriceStats    `json:"stats,omitempty"` // nil when no pharmacy sells the product
+}
+
+// One pharmacy's offer of a product and whether the pharmacy is open
+type MaskOffer struct {
+	MaskID       uint       `json:"mask_id"`
+	PharmacyID   uint       `json:"pharmacy_id"`
+	PharmacyName string     `json:"pharmacy_name"`
+	Price        float64    `json:"price"`
+	PricePerUnit float64    `json:"price_per_unit"`
+	Sales        int64      `json:"sales,omitempty"` // when sorted by popularity
+	OpenNow      bool       `json:"open_now"`
+	ClosesAt     *time.Time `json:"closes_at,omitempty"`
+	NextOpenAt   *time.Time `json:"next_open_at,omitempty"`
+}
+
+// Lowest, average and highest price of the offers of a product
+type PriceStats struct {
+	MinPrice        float64 `json:"min_price"`
+	AvgPrice        float64 `json:"avg_price"`
+	MaxPrice        float64 `json:"max_price"`
+	MinPricePerUnit float64 `json:"min_price_per_unit"`
+	AvgPricePerUnit float64 `json:"avg_price_per_unit"`
+	MaxPricePerUnit float64 `json:"max_price_per_unit"`
+}
+
+// 16. Suggest Response
+type SuggestResponse struct {
+	Query       string               `json:"q"`
+	Suggestions []suggest.Suggestion `json:"suggestions"`
+	Count       int                  `json:"count"`
+	IndexedAt   time.Time            `json:"indexed_at"`
+}
+
+// 17. Nearby Pharmacies Response
+type NearbyPharmaciesResponse struct {
+	Latitude   float64          `json:"lat"`
+	Longitude  float64          `json:"lng"`
+	RadiusKm   float64          `json:"radius_km"`
+	At         time.Time        `json:"at"`
+	Pharmacies []NearbyPharmacy `json:"pharmacies"`
+	Count      int              `json:"count"`
+	PageInfo
+}
+
+type NearbyPharmacy struct {
+	PharmacyAvailability
+	DistanceKm float64 `json:"distance_km"`
 }
 
 // 8. Health check response
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"PhantomBE/global"
+)
+
+// 1. Request structure for creating a promotion
+type CreatePromotionRequest struct {
+	Code              string  `json:"code" binding:"required,coupon_code" validate_msg:"Code is required and must be 3-32 letters, digits, hyphens or underscores"`
+	Description       string  `json:"description" binding:"max=255" validate_msg:"Description cannot exceed 255 characters"`
+	DiscountType      string  `json:"discount_type" binding:"required,valid_discount_type" validate_msg:"Discount type must be 'percentage', 'fixed' or 'buy_x_get_y'"`
+	DiscountValue     float64 `json:"discount_value" binding:"min=0" validate_msg:"Discount value cannot be negative, and a percentage cannot exceed 100"`
+	BuyQuantity       int     `json:"buy_quantity" binding:"non_negative_int" validate_msg:"Buy quantity is required for buy_x_get_y and cannot be negative"`
+	FreeQuantity      int     `json:"free_quantity" binding:"non_negative_int" validate_msg:"Free quantity is required for buy_x_get_y and cannot be negative"`
+	UserID            *uint   `json:"user_id,omitempty" binding:"omitempty,positive_uint" validate_msg:"User ID must be a positive number"`
+	PharmacyID        *uint   `json:"pharmacy_id,omitempty" binding:"omitempty,positive_uint" validate_msg:"Pharmacy ID must be a positive number"`
+	MaskNamePattern   string  `json:"mask_name_pattern,omitempty" binding:"max=100" validate_msg:"Mask name pattern cannot exceed 100 characters"`
+	FirstPurchaseOnly bool    `json:"first_purchase_only"`
+	MinQuantity       int     `json:"min_quantity" binding:"non_negative_int" validate_msg:"Min quantity cannot be negative"`
+	StartDate         string  `json:"start_date,omitempty" binding:"omitempty,date_format" validate_msg:"Start date must be in YYYY-MM-DD format"`
+	EndDate           string  `json:"end_date,omitempty" binding:"omitempty,date_format" validate_msg:"End date must be in YYYY-MM-DD format and must be after start date"`
+	MaxUses           int     `json:"max_uses" binding:"non_negative_int" validate_msg:"Max uses cannot be negative"`
+	MaxUsesPerUser    int     `json:"max_uses_per_user" binding:"non_negative_int" validate_msg:"Max uses per user cannot be negative"`
+}
+
+// 2. Request structure for previewing a promotion against a prospective purchase
+type PromotionPreviewRequest struct {
+	Code       string `json:"code" binding:"required,coupon_code" validate_msg:"Code is required and must be 3-32 letters, digits, hyphens or underscores"`
+	UserID     uint   `json:"user_id" binding:"required,positive_uint" validate_msg:"User ID must be a positive number"`
+	PharmacyID uint   `json:"pharmacy_id" binding:"required,positive_uint" validate_msg:"Pharmacy ID must be a positive number"`
+	MaskID     uint   `json:"mask_id" binding:"required,positive_uint" validate_msg:"Mask ID must be a positive number"`
+	Quantity   int    `json:"quantity" binding:"required,positive_int,max_quantity" validate_msg:"Quantity must be between 1 and 1000"`
+}
+
+// Response structure
+
+// 1. Promotion Response
+type PromotionResponse struct {
+	Promotion global.Promotion `json:"promotion"`
+}
+
+type PromotionListResponse struct {
+	Promotions []global.Promotion `json:"promotions"`
+	Count      int                `json:"count"`
+}
+
+// 2. Promotion Preview Response
+type PromotionPreviewResponse struct {
+	Code           string  `json:"code"`
+	Eligible       bool    `json:"eligible"`
+	Reason         string  `json:"reason,omitempty"` // error code explaining why the code does not apply
+	UnitPrice      float64 `json:"unit_price"`
+	Quantity       int     `json:"quantity"`
+	OriginalAmount float64 `json:"original_amount"`
+	DiscountAmount float64 `json:"discount_amount"`
+	FinalAmount    float64 `json:"final_amount"`
+}
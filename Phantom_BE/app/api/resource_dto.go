@@ -0,0 +1,66 @@
+package api
+
+import (
+	"PhantomBE/app/schedule"
+	"PhantomBE/global"
+)
+
+// Request structures of the v2 resource API are bound from the query string;
+// path parameters are filled in by the handler.
+
+// 1. Query structure for the purchases of a user
+type UserPurchasesRequest struct {
+	PageRequest
+	UserID     uint   `form:"-" binding:"required,positive_uint" validate_msg:"User ID must be a positive number"` // path parameter
+	StartDate  string `form:"start_date" binding:"omitempty,date_format" validate_msg:"Start date must be in YYYY-MM-DD format"`
+	EndDate    string `form:"end_date" binding:"omitempty,date_format" validate_msg:"End date must be in YYYY-MM-DD format and must be after start date"`
+	PharmacyID uint   `form:"pharmacy_id" binding:"omitempty,positive_uint" validate_msg:"Pharmacy ID must be a positive number"`
+}
+
+// 2. Query structure for the pharmacy list
+type PharmacyListRequest struct {
+	PageRequest
+	PharmacyFields
+}
+
+// Response structure
+
+// 1. Pharmacy List Response
+type PharmacyListResponse struct {
+	Pharmacies []global.Pharmacy `json:"pharmacies"`
+	Count      int               `json:"count"`
+	PageInfo
+}
+
+// 2. Pharmacy Response
+type PharmacyResponse struct {
+	Pharmacy            PharmacyAvailability `json:"pharmacy"`
+	OpeningHoursChanges []schedule.Change    `json:"opening_hours_changes,omitempty"` // made normalizing the hours on create
+}
+
+// 3. Mask Response
+type MaskResponse struct {
+	Mask global.Mask `json:"mask"`
+}
+
+// 4. User Response
+type UserResponse struct {
+	User global.User `json:"user"`
+}
+
+// 5. User Purchases Response
+type UserPurchasesResponse struct {
+	UserID    uint              `json:"user_id"`
+	UserName  string            `json:"user_name"`
+	Purchases []global.Purchase `json:"purchases"`
+	Count     int               `json:"count"`
+	PageInfo
+}
+
+// 6. Pharmacy Schedule Response
+type PharmacyScheduleResponse struct {
+	PharmacyID   uint                   `json:"pharmacy_id"`
+	PharmacyName string                 `json:"pharmacy_name"`
+	Schedule     []schedule.DaySchedule `json:"schedule"`
+	Text         string                 `json:"text"` // e.g. "Mon - Fri 08:00 - 17:00 / Sat 10:00 - 14:00"
+}
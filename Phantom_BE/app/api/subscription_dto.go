@@ -0,0 +1,57 @@
+package api
+
+import (
+	"PhantomBE/global"
+)
+
+// 1. Request structure for creating a subscription
+type CreateSubscriptionRequest struct {
+	UserID     uint   `json:"user_id" binding:"required,positive_uint" validate_msg:"User ID must be a positive number"`
+	PharmacyID uint   `json:"pharmacy_id" binding:"required,positive_uint" validate_msg:"Pharmacy ID must be a positive number"`
+	MaskID     uint   `json:"mask_id" binding:"required,positive_uint" validate_msg:"Mask ID must be a positive number"`
+	Quantity   int    `json:"quantity" binding:"required,positive_int,max_quantity" validate_msg:"Quantity must be between 1 and 1000"`
+	Frequency  string `json:"frequency" binding:"required,valid_frequency" validate_msg:"Frequency must be 'daily', 'weekly' or 'monthly'"`
+	Every      int    `json:"every" binding:"non_negative_int,max=12" validate_msg:"Every must be between 0 and 12"`
+	StartAt    string `json:"start_at,omitempty" binding:"omitempty,datetime_format" validate_msg:"Start at must be in YYYY-MM-DD HH:MM format"`
+}
+
+// 2. Query structure for listing subscriptions
+type ListSubscriptionsRequest struct {
+	UserID uint   `form:"user_id" binding:"omitempty,positive_uint" validate_msg:"User ID must be a positive number"`
+	Status string `form:"status" binding:"omitempty,valid_subscription_status" validate_msg:"Status must be 'active', 'paused' or 'cancelled'"`
+	Limit  int    `form:"limit" binding:"non_negative_int,max=500" validate_msg:"Limit must be between 0 and 500"`
+}
+
+// 3. Request structure for updating a subscription, omitted fields are left unchanged
+type UpdateSubscriptionRequest struct {
+	Quantity  *int    `json:"quantity,omitempty" binding:"omitempty,positive_int,max_quantity" validate_msg:"Quantity must be between 1 and 1000"`
+	Frequency *string `json:"frequency,omitempty" binding:"omitempty,valid_frequency" validate_msg:"Frequency must be 'daily', 'weekly' or 'monthly'"`
+	Every     *int    `json:"every,omitempty" binding:"omitempty,positive_int,max=12" validate_msg:"Every must be between 1 and 12"`
+	NextRunAt *string `json:"next_run_at,omitempty" binding:"omitempty,datetime_format" validate_msg:"Next run at must be in YYYY-MM-DD HH:MM format"`
+	Status    *string `json:"status,omitempty" binding:"omitempty,oneof=active paused" validate_msg:"Status must be 'active' or 'paused'"`
+}
+
+// 4. Query structure for the run history of a subscription
+type SubscriptionRunsRequest struct {
+	Limit int `form:"limit" binding:"non_negative_int,max=500" validate_msg:"Limit must be between 0 and 500"`
+}
+
+// Response structure
+
+// 1. Subscription Response
+type SubscriptionResponse struct {
+	Subscription global.Subscription `json:"subscription"`
+}
+
+// 2. Subscription List Response
+type SubscriptionListResponse struct {
+	Subscriptions []global.Subscription `json:"subscriptions"`
+	Count         int                   `json:"count"`
+}
+
+// 4. Subscription Runs Response
+type SubscriptionRunsResponse struct {
+	SubscriptionID uint                     `json:"subscription_id"`
+	Runs           []global.SubscriptionRun `json:"runs"`
+	Count          int                      `json:"count"`
+}
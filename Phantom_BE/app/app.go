@@ -4,11 +4,14 @@ package app
 import (
 
 	"PhantomBE/app/routes"
+	"PhantomBE/app/controllers"
 	"PhantomBE/global"
 	"PhantomBE/app/models"
 	"PhantomBE/app/initial"
 	"PhantomBE/app/middleware"
 	"PhantomBE/app/validation"
+	"PhantomBE/app/suggest"
+	"context"
 	"time"
 	"github.com/charmbracelet/log"
 	"github.com/fvbock/endless"
@@ -38,13 +41,19 @@ func StartApplication() {
 	// 5. Configure routes and routing groups (./router.go)
 	routes.ConfigureRoutes()
 
-	// 6. Configure http server
+	// 6. Start the subscription scheduler and suggest index refresher, they stop with the server
+	ctx, stopScheduler := context.WithCancel(context.Background())
+	controllers.NewSubscriptionScheduler(models.DBPharmacy, global.SubscriptionSchedulerInterval).Start(ctx)
+	suggest.NewRefresher(models.DBPharmacy, suggest.Default, global.SuggestRefreshInterval).Start(ctx)
+
+	// 7. Configure http server
 	addr := global.GinAddr
 
 	err := endless.ListenAndServe(addr, routes.Router)
 	if err != nil {
 		log.Warn(err)
 	}
+	stopScheduler()
 	log.Info("Server on %v stopped", addr)
 
 	// If the server stops, close the database connections
@@ -0,0 +1,99 @@
+package catalog
+
+import (
+	"PhantomBE/global"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// catalog.go reads the attributes encoded in mask product names, e.g.
+// "True Barrier (green) (3 per pack)" is brand "True Barrier", color "green"
+// and 3 units per pack, and links pharmacy offers to the shared products.
+
+// Attributes are the structured parts of a mask name
+type Attributes struct {
+	Brand    string
+	Color    string
+	PackSize int // units per pack, 1 when the name does not say
+}
+
+var (
+	groupPattern = regexp.MustCompile(`\(([^()]*)\)`)
+	packPattern  = regexp.MustCompile(`(?i)^\s*(\d+)\s*(?:per|/)\s*pack\s*$`)
+)
+
+// ParseName splits a mask name into brand (the text before the first
+// parenthesis), color (the first other parenthesized word, lower-cased) and
+// pack size (a "(N per pack)" group).
+func ParseName(name string) Attributes {
+	attributes := Attributes{PackSize: 1}
+
+	brand := name
+	if i := strings.Index(name, "("); i >= 0 {
+		brand = name[:i]
+	}
+	attributes.Brand = strings.TrimSpace(brand)
+
+	for _, group := range groupPattern.FindAllStringSubmatch(name, -1) {
+		if match := packPattern.FindStringSubmatch(group[1]); match != nil {
+			if size, err := strconv.Atoi(match[1]); err == nil && size > 0 {
+				attributes.PackSize = size
+			}
+			continue
+		}
+		if attributes.Color == "" {
+			attributes.Color = strings.ToLower(strings.TrimSpace(group[1]))
+		}
+	}
+	return attributes
+}
+
+// PricePerUnit is the price of one mask of a pack, rounded to 4 decimal places
+func PricePerUnit(price float64, packSize int) float64 {
+	if packSize < 1 {
+		packSize = 1
+	}
+	return math.Round(price/float64(packSize)*10000) / 10000
+}
+
+// Describe fills the brand, color, pack size and price per unit of mask from
+// its name and price
+func Describe(mask *global.Mask) {
+	attributes := ParseName(mask.Name)
+	mask.Brand = attributes.Brand
+	mask.Color = attributes.Color
+	mask.PackSize = attributes.PackSize
+	mask.PricePerUnit = PricePerUnit(mask.Price, attributes.PackSize)
+}
+
+// Link describes mask and points it at the product with the same name,
+// creating the product on first use
+func Link(db *gorm.DB, mask *global.Mask) error {
+	Describe(mask)
+	product := global.Product{
+		Name:     mask.Name,
+		Brand:    mask.Brand,
+		Color:    mask.Color,
+		PackSize: mask.PackSize,
+	}
+	// Another request may create the same product concurrently
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoNothing: true,
+	}).Create(&product).Error
+	if err != nil {
+		return err
+	}
+	if product.ID == 0 {
+		if err := db.Where("name = ?", mask.Name).First(&product).Error; err != nil {
+			return err
+		}
+	}
+	mask.ProductID = product.ID
+	return nil
+}
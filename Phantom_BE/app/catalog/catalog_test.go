@@ -0,0 +1,31 @@
+package catalog
+
+import (
+	"PhantomBE/global"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected Attributes
+	}{
+		{"True Barrier (green) (3 per pack)", Attributes{Brand: "True Barrier", Color: "green", PackSize: 3}},
+		{"MaskT (Black) (10 per pack)", Attributes{Brand: "MaskT", Color: "black", PackSize: 10}},
+		{"Second Smile (6 per pack) (blue)", Attributes{Brand: "Second Smile", Color: "blue", PackSize: 6}},
+		{"Cotton Kiss", Attributes{Brand: "Cotton Kiss", PackSize: 1}},
+	}
+	for _, tc := range cases {
+		if result := ParseName(tc.name); result != tc.expected {
+			t.Errorf("ParseName(%q): expected %+v, got %+v", tc.name, tc.expected, result)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	mask := global.Mask{Name: "True Barrier (green) (3 per pack)", Price: 13.7}
+	Describe(&mask)
+	if mask.Brand != "True Barrier" || mask.Color != "green" || mask.PackSize != 3 || mask.PricePerUnit != 4.5667 {
+		t.Errorf("Unexpected attributes %+v", mask)
+	}
+}
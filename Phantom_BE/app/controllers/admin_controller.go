@@ -0,0 +1,693 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"PhantomBE/app/api"
+	"PhantomBE/app/catalog"
+	"PhantomBE/app/schedule"
+	"PhantomBE/app/suggest"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdminController manages pharmacies, their masks and opening hours.
+// All routes are behind middleware.IsSysAdm.
+type AdminController struct {
+	db *gorm.DB
+}
+
+func NewAdminController(db *gorm.DB) *AdminController {
+	return &AdminController{db: db}
+}
+
+// 1. Create a pharmacy, optionally with its opening hours and masks
+// POST /api/v1/admin/pharmacies
+func (ac *AdminController) CreatePharmacy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.CreatePharmacyRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	hours, changes := openingHoursFromRequest(req.OpeningHours)
+	pharmacy := global.Pharmacy{
+		Name:                 strings.TrimSpace(req.Name),
+		CashBalance:          req.CashBalance,
+		CommissionPercent:    req.CommissionPercent,
+		CommissionFixedFee:   req.CommissionFixedFee,
+		AcceptsOrdersAnytime: req.AcceptsOrdersAnytime,
+		ObservesHolidays:     req.ObservesHolidays,
+		Address:              strings.TrimSpace(req.Address),
+		Latitude:             req.Latitude,
+		Longitude:            req.Longitude,
+		OpeningHours:         hours,
+	}
+	for _, mask := range req.Masks {
+		pharmacy.Masks = append(pharmacy.Masks, global.Mask{
+			Name:  strings.TrimSpace(mask.Name),
+			Price: mask.Price,
+		})
+	}
+
+	// Pharmacy names identify pharmacies in the data import and purchase history
+	if !ac.ensureUniqueName(c, pharmacy.Name, 0) {
+		return
+	}
+
+	// Products are only kept when the pharmacy offering them is created
+	err := ac.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range pharmacy.Masks {
+			if err := catalog.Link(tx, &pharmacy.Masks[i]); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&pharmacy).Error
+	})
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	// Pharmacy and mask names are suggested from an in-memory index
+	suggest.Default.MarkStale()
+	ac.respondWithPharmacy(c, http.StatusCreated, pharmacy, changes)
+}
+
+// 2. Update the name, balance, commission, order availability, holiday opt-in or location of a pharmacy
+// PUT /api/v1/admin/pharmacies/:id
+func (ac *AdminController) UpdatePharmacy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.UpdatePharmacyRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if !ac.ensureUniqueName(c, name, pharmacy.ID) {
+			return
+		}
+		updates["name"] = name
+	}
+	if req.CashBalance != nil {
+		updates["cash_balance"] = *req.CashBalance
+	}
+	if req.CommissionPercent != nil {
+		updates["commission_percent"] = *req.CommissionPercent
+	}
+	if req.CommissionFixedFee != nil {
+		updates["commission_fixed_fee"] = *req.CommissionFixedFee
+	}
+	if req.AcceptsOrdersAnytime != nil {
+		updates["accepts_orders_anytime"] = *req.AcceptsOrdersAnytime
+	}
+	if req.ObservesHolidays != nil {
+		updates["observes_holidays"] = *req.ObservesHolidays
+	}
+	if req.Address != nil {
+		updates["address"] = strings.TrimSpace(*req.Address)
+	}
+	if req.Latitude != nil {
+		updates["latitude"] = *req.Latitude
+		updates["longitude"] = *req.Longitude
+	}
+
+	if len(updates) > 0 {
+		if err := ac.db.WithContext(ctx).Model(&pharmacy).Omit(clause.Associations).Updates(updates).Error; err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+	}
+
+	suggest.Default.MarkStale()
+	ac.respondWithPharmacy(c, http.StatusOK, pharmacy, nil)
+}
+
+// 3. Delete a pharmacy with its masks, opening hours and overrides, cancelling its subscriptions.
+// Purchase history keeps the pharmacy name.
+// DELETE /api/v1/admin/pharmacies/:id
+func (ac *AdminController) DeletePharmacy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	err := ac.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		maskIDs := tx.Model(&global.Mask{}).Select("id").Where("pharmacy_id = ?", pharmacy.ID)
+		if err := tx.Where("mask_id IN (?)", maskIDs).Delete(&global.MaskPriceTier{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("pharmacy_id = ?", pharmacy.ID).Delete(&global.Mask{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("pharmacy_id = ?", pharmacy.ID).Delete(&global.OpeningHour{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("pharmacy_id = ?", pharmacy.ID).Delete(&global.OpeningOverride{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&global.Subscription{}).
+			Where("pharmacy_id = ? AND status <> ?", pharmacy.ID, "cancelled").
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		return tx.Delete(&pharmacy).Error
+	})
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.DeleteResponse{
+		Success: true,
+		Message: "Pharmacy deleted",
+		ID:      pharmacy.ID,
+	}
+	suggest.Default.MarkStale()
+	c.JSON(http.StatusOK, response)
+}
+
+// 4. Add a mask to a pharmacy
+// POST /api/v1/admin/pharmacies/:id/masks
+func (ac *AdminController) CreateMask(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.MaskRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	mask := global.Mask{
+		Name:       strings.TrimSpace(req.Name),
+		Price:      req.Price,
+		PharmacyID: pharmacy.ID,
+	}
+	err := ac.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := catalog.Link(tx, &mask); err != nil {
+			return err
+		}
+		return tx.Create(&mask).Error
+	})
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	suggest.Default.MarkStale()
+	c.JSON(http.StatusCreated, api.MaskResponse{Mask: mask})
+}
+
+// 5. Rename or reprice a mask
+// PUT /api/v1/admin/masks/:id
+func (ac *AdminController) UpdateMask(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.UpdateMaskRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	mask, ok := ac.findMask(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = strings.TrimSpace(*req.Name)
+		mask.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Price != nil {
+		updates["price"] = *req.Price
+		mask.Price = *req.Price
+	}
+	if len(updates) > 0 {
+		// Attributes follow the name and price, and a new name is another product
+		err := ac.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if req.Name != nil {
+				if err := catalog.Link(tx, &mask); err != nil {
+					return err
+				}
+				updates["product_id"] = mask.ProductID
+			}
+			catalog.Describe(&mask)
+			updates["brand"] = mask.Brand
+			updates["color"] = mask.Color
+			updates["pack_size"] = mask.PackSize
+			updates["price_per_unit"] = mask.PricePerUnit
+			return tx.Model(&mask).Updates(updates).Error
+		})
+		if err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+	}
+
+	suggest.Default.MarkStale()
+	c.JSON(http.StatusOK, api.MaskResponse{Mask: mask})
+}
+
+// 6. Remove a mask and its price tiers. Subscriptions to it fail as out of stock.
+// DELETE /api/v1/admin/masks/:id
+func (ac *AdminController) DeleteMask(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	mask, ok := ac.findMask(c)
+	if !ok {
+		return
+	}
+
+	err := ac.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mask_id = ?", mask.ID).Delete(&global.MaskPriceTier{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&mask).Error
+	})
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.DeleteResponse{
+		Success: true,
+		Message: "Mask deleted",
+		ID:      mask.ID,
+	}
+	suggest.Default.MarkStale()
+	c.JSON(http.StatusOK, response)
+}
+
+// 7. Replace the weekly opening hours of a pharmacy
+// PUT /api/v1/admin/pharmacies/:id/opening-hours
+func (ac *AdminController) ReplaceOpeningHours(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.OpeningHoursRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	hours, changes := openingHoursFromRequest(&req)
+	for i := range hours {
+		hours[i].PharmacyID = pharmacy.ID
+	}
+
+	// Swap the whole schedule atomically
+	err := ac.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("pharmacy_id = ?", pharmacy.ID).Delete(&global.OpeningHour{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.OpeningHoursResponse{
+		PharmacyID:   pharmacy.ID,
+		PharmacyName: pharmacy.Name,
+		OpeningHours: hours,
+		Changes:      changes,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 8. List the date-specific overrides of a pharmacy, optionally within a date range
+// GET /api/v1/admin/pharmacies/:id/overrides?from=&to=
+func (ac *AdminController) ListOpeningOverrides(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.OpeningOverridesRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	query := ac.db.WithContext(ctx).Where("pharmacy_id = ?", pharmacy.ID)
+	if req.From != "" {
+		query = query.Where("date >= ?", req.From)
+	}
+	if req.To != "" {
+		query = query.Where("date <= ?", req.To)
+	}
+
+	overrides := []global.OpeningOverride{}
+	if err := query.Order("date").Find(&overrides).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.OpeningOverridesResponse{
+		PharmacyID:   pharmacy.ID,
+		PharmacyName: pharmacy.Name,
+		Overrides:    overrides,
+		Count:        len(overrides),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 9. Close a pharmacy or replace its hours on one date
+// PUT /api/v1/admin/pharmacies/:id/overrides/:date
+func (ac *AdminController) SetOpeningOverride(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.OpeningOverrideRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	date, ok := parseDateParam(c, "date")
+	if !ok {
+		return
+	}
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	override := global.OpeningOverride{
+		PharmacyID: pharmacy.ID,
+		Date:       date,
+		Closed:     req.Closed,
+		Reason:     strings.TrimSpace(req.Reason),
+	}
+	var shifts []global.Shift
+	for _, shift := range req.Shifts {
+		open, _ := schedule.ParseClock(shift.Open)
+		close, _ := schedule.ParseClock(shift.Close)
+		shifts = append(shifts, global.Shift{OpenTime: open, CloseTime: close})
+	}
+	normalized, changes := schedule.NormalizeShifts(date, shifts)
+	override.Shifts = normalized
+	if !override.Closed && len(override.Shifts) == 0 {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error:   "Override has no shifts left after normalizing, set closed instead",
+			Code:    "INVALID_INPUT",
+			Details: changes,
+		})
+		return
+	}
+
+	// One override per pharmacy and date, a new one replaces the old
+	if err := ac.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pharmacy_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"closed", "shifts", "reason"}),
+	}).Create(&override).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.OpeningOverrideResponse{
+		Override: override,
+		Changes:  changes,
+	})
+}
+
+// 10. Remove the override of a pharmacy on one date, restoring its weekly hours
+// DELETE /api/v1/admin/pharmacies/:id/overrides/:date
+func (ac *AdminController) DeleteOpeningOverride(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	date, ok := parseDateParam(c, "date")
+	if !ok {
+		return
+	}
+
+	pharmacy, ok := ac.findPharmacy(c)
+	if !ok {
+		return
+	}
+
+	result := ac.db.WithContext(ctx).
+		Where("pharmacy_id = ? AND date = ?", pharmacy.ID, date).
+		Delete(&global.OpeningOverride{})
+	if result.Error != nil {
+		abortWithDBError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, global.ErrorResponse{
+			Error: "Override not found",
+			Code:  "OVERRIDE_NOT_FOUND",
+			Details: gin.H{
+				"pharmacy_id": pharmacy.ID,
+				"date":        date,
+			},
+		})
+		return
+	}
+
+	response := api.DeleteResponse{
+		Success: true,
+		Message: "Override deleted",
+		ID:      pharmacy.ID,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 11. List the national holiday calendar, optionally for one year
+// GET /api/v1/admin/holidays?year=
+func (ac *AdminController) ListHolidays(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.HolidaysRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	query := ac.db.WithContext(ctx)
+	if req.Year != 0 {
+		query = query.Where("date BETWEEN ? AND ?", fmt.Sprintf("%04d-01-01", req.Year), fmt.Sprintf("%04d-12-31", req.Year))
+	}
+
+	holidays := []global.Holiday{}
+	if err := query.Order("date").Find(&holidays).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.HolidaysResponse{
+		Holidays: holidays,
+		Count:    len(holidays),
+	})
+}
+
+// 12. Add or rename a date of the national holiday calendar
+// PUT /api/v1/admin/holidays/:date
+func (ac *AdminController) SetHoliday(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.HolidayRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	date, ok := parseDateParam(c, "date")
+	if !ok {
+		return
+	}
+
+	holiday := global.Holiday{
+		Date: date,
+		Name: strings.TrimSpace(req.Name),
+	}
+	if err := ac.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name"}),
+	}).Create(&holiday).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, holiday)
+}
+
+// 13. Remove a date from the national holiday calendar
+// DELETE /api/v1/admin/holidays/:date
+func (ac *AdminController) DeleteHoliday(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	date, ok := parseDateParam(c, "date")
+	if !ok {
+		return
+	}
+
+	var holiday global.Holiday
+	if err := ac.db.WithContext(ctx).Where("date = ?", date).First(&holiday).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Holiday not found",
+				Code:  "HOLIDAY_NOT_FOUND",
+				Details: gin.H{
+					"date": date,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	if err := ac.db.WithContext(ctx).Delete(&holiday).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.DeleteResponse{
+		Success: true,
+		Message: "Holiday deleted",
+		ID:      holiday.ID,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// openingHoursFromRequest turns validated structured or raw opening hours into
+// normalized entries, reporting what normalizing changed
+func openingHoursFromRequest(req *api.OpeningHoursRequest) ([]global.OpeningHour, []schedule.Change) {
+	if req == nil {
+		return nil, nil
+	}
+	if req.Raw != "" {
+		return schedule.Normalize(schedule.ParseOpeningHours(req.Raw))
+	}
+	hours := make([]global.OpeningHour, 0, len(req.Entries))
+	for _, entry := range req.Entries {
+		day, _ := schedule.ParseDay(entry.Day)
+		open, _ := schedule.ParseClock(entry.Open)
+		close, _ := schedule.ParseClock(entry.Close)
+		hours = append(hours, global.OpeningHour{
+			DayOfWeek: day,
+			OpenTime:  open,
+			CloseTime: close,
+		})
+	}
+	return schedule.Normalize(hours)
+}
+
+// ensureUniqueName rejects a pharmacy name already used by another pharmacy
+func (ac *AdminController) ensureUniqueName(c *gin.Context, name string, pharmacyID uint) bool {
+	var count int64
+	if err := ac.db.WithContext(c.Request.Context()).
+		Model(&global.Pharmacy{}).
+		Where("name = ? AND id <> ?", name, pharmacyID).
+		Count(&count).Error; err != nil {
+		abortWithDBError(c, err)
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, global.ErrorResponse{
+			Error: "Pharmacy name already exists",
+			Code:  "PHARMACY_NAME_EXISTS",
+			Details: gin.H{
+				"name": name,
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// respondWithPharmacy writes the pharmacy with whether it is open now and the
+// changes made normalizing its opening hours
+func (ac *AdminController) respondWithPharmacy(c *gin.Context, status int, pharmacy global.Pharmacy, changes []schedule.Change) {
+	now := time.Now().In(global.BusinessLocation())
+	calendars, err := loadCalendars(ac.db.WithContext(c.Request.Context()), []global.Pharmacy{pharmacy}, now, now)
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(status, api.PharmacyResponse{
+		Pharmacy:            availabilityAt(pharmacy, calendars[pharmacy.ID], now),
+		OpeningHoursChanges: changes,
+	})
+}
+
+// findPharmacy loads the pharmacy named by the ":id" path parameter, writing the
+// error response when it cannot.
+func (ac *AdminController) findPharmacy(c *gin.Context) (global.Pharmacy, bool) {
+	var pharmacy global.Pharmacy
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return pharmacy, false
+	}
+
+	if err := ac.db.WithContext(c.Request.Context()).Preload("OpeningHours").First(&pharmacy, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Pharmacy not found",
+				Code:  "PHARMACY_NOT_FOUND",
+				Details: gin.H{
+					"pharmacy_id": id,
+				},
+			})
+			return pharmacy, false
+		}
+		abortWithDBError(c, err)
+		return pharmacy, false
+	}
+	return pharmacy, true
+}
+
+// findMask loads the mask named by the ":id" path parameter, writing the error
+// response when it cannot.
+func (ac *AdminController) findMask(c *gin.Context) (global.Mask, bool) {
+	var mask global.Mask
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return mask, false
+	}
+
+	if err := ac.db.WithContext(c.Request.Context()).First(&mask, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Mask not found",
+				Code:  "MASK_NOT_FOUND",
+				Details: gin.H{
+					"mask_id": id,
+				},
+			})
+			return mask, false
+		}
+		abortWithDBError(c, err)
+		return mask, false
+	}
+	return mask, true
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"math"
+	"gorm.io/gorm"
+)
+
+// commission describes the platform fee charged on a single purchase
+type commission struct {
+	Percent  float64
+	FixedFee float64
+	Amount   float64
+}
+
+// calculateCommission applies the pharmacy's commission override (or the platform
+// default) to the purchase total. The fee never exceeds the total amount.
+func calculateCommission(pharmacy global.Pharmacy, totalAmount float64) commission {
+	percent := global.CommissionPercent
+	if pharmacy.CommissionPercent != nil {
+		percent = *pharmacy.CommissionPercent
+	}
+	fixedFee := global.CommissionFixedFee
+	if pharmacy.CommissionFixedFee != nil {
+		fixedFee = *pharmacy.CommissionFixedFee
+	}
+
+	amount := roundCurrency(totalAmount*percent/100 + fixedFee)
+	if amount > totalAmount {
+		amount = totalAmount
+	}
+	if amount < 0 {
+		amount = 0
+	}
+	return commission{
+		Percent:  percent,
+		FixedFee: fixedFee,
+		Amount:   amount,
+	}
+}
+
+// splitAmount spreads total over n purchase records in whole cents,
+// the last record absorbs the rounding remainder.
+func splitAmount(total float64, n int) []float64 {
+	parts := make([]float64, n)
+	if n == 0 {
+		return parts
+	}
+	cents := int64(math.Round(total * 100))
+	share := cents / int64(n)
+	for i := range parts {
+		parts[i] = float64(share) / 100
+	}
+	parts[n-1] = float64(cents-share*int64(n-1)) / 100
+	return parts
+}
+
+// creditPlatformAccount adds amount to the platform account inside tx,
+// creating the account on first use.
+func creditPlatformAccount(tx *gorm.DB, amount float64) error {
+	account := global.PlatformAccount{Name: global.PlatformAccountName}
+	if err := tx.Where("name = ?", account.Name).FirstOrCreate(&account).Error; err != nil {
+		return err
+	}
+	if amount == 0 {
+		return nil
+	}
+	// Increment in SQL so concurrent purchases never overwrite each other
+	return tx.Model(&global.PlatformAccount{}).
+		Where("id = ?", account.ID).
+		Update("cash_balance", gorm.Expr("cash_balance + ?", amount)).Error
+}
+
+func roundCurrency(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
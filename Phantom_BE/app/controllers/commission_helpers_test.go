@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"PhantomBE/global"
+)
+
+func TestCalculateCommission(t *testing.T) {
+	defaultPercent, defaultFee := global.CommissionPercent, global.CommissionFixedFee
+	defer func() { global.CommissionPercent, global.CommissionFixedFee = defaultPercent, defaultFee }()
+	global.CommissionPercent = 5
+	global.CommissionFixedFee = 0.5
+
+	result := calculateCommission(global.Pharmacy{}, 137)
+	if result.Amount != 7.35 {
+		t.Errorf("Expected default commission 7.35, got %v", result.Amount)
+	}
+
+	percent, fee := 10.0, 0.0
+	result = calculateCommission(global.Pharmacy{CommissionPercent: &percent, CommissionFixedFee: &fee}, 137)
+	if result.Amount != 13.7 {
+		t.Errorf("Expected override commission 13.7, got %v", result.Amount)
+	}
+
+	result = calculateCommission(global.Pharmacy{}, 0.2)
+	if result.Amount != 0.2 {
+		t.Errorf("Expected commission capped at 0.2, got %v", result.Amount)
+	}
+}
+
+func TestSplitAmount(t *testing.T) {
+	expected := []float64{0.33, 0.33, 0.34}
+	result := splitAmount(1, 3)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
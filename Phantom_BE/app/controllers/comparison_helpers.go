@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"PhantomBE/app/catalog"
+)
+
+// summarizeOffers returns the lowest, average and highest price and price per
+// unit of offers, or nil when there are none
+func summarizeOffers(offers []api.MaskOffer) *api.PriceStats {
+	if len(offers) == 0 {
+		return nil
+	}
+	stats := api.PriceStats{
+		MinPrice:        offers[0].Price,
+		MaxPrice:        offers[0].Price,
+		MinPricePerUnit: offers[0].PricePerUnit,
+		MaxPricePerUnit: offers[0].PricePerUnit,
+	}
+	var total, totalPerUnit float64
+	for _, offer := range offers {
+		stats.MinPrice = min(stats.MinPrice, offer.Price)
+		stats.MaxPrice = max(stats.MaxPrice, offer.Price)
+		stats.MinPricePerUnit = min(stats.MinPricePerUnit, offer.PricePerUnit)
+		stats.MaxPricePerUnit = max(stats.MaxPricePerUnit, offer.PricePerUnit)
+		total += offer.Price
+		totalPerUnit += offer.PricePerUnit
+	}
+	stats.AvgPrice = roundCurrency(total / float64(len(offers)))
+	// Per unit prices keep the precision of catalog.PricePerUnit
+	stats.AvgPricePerUnit = catalog.PricePerUnit(totalPerUnit/float64(len(offers)), 1)
+	return &stats
+}
+
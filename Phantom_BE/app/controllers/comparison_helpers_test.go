@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"testing"
+)
+
+func TestSummarizeOffers(t *testing.T) {
+	if stats := summarizeOffers(nil); stats != nil {
+		t.Errorf("Expected no stats without offers, got %+v", stats)
+	}
+
+	offers := []api.MaskOffer{
+		{Price: 5.84, PricePerUnit: 1.9467},
+		{Price: 10, PricePerUnit: 3.3333},
+		{Price: 7.5, PricePerUnit: 2.5},
+	}
+	stats := summarizeOffers(offers)
+	expected := api.PriceStats{
+		MinPrice:        5.84,
+		AvgPrice:        7.78,
+		MaxPrice:        10,
+		MinPricePerUnit: 1.9467,
+		AvgPricePerUnit: 2.5933,
+		MaxPricePerUnit: 3.3333,
+	}
+	if stats == nil || *stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
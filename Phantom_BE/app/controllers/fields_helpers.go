@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"PhantomBE/global"
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pharmacyAssociations maps the include names of pharmacy associations to their gorm names
+var pharmacyAssociations = map[string]string{
+	"masks":         "Masks",
+	"opening_hours": "OpeningHours",
+}
+
+// splitList splits a comma-separated list into its trimmed, lowercased and
+// distinct items
+func splitList(list string) []string {
+	var items []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" && !seen[item] {
+			seen[item] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// pharmacyColumns is the select list of the pharmacy columns in fields.Fields,
+// all of them when it is empty. The ID and the required columns are always
+// read, e.g. observes_holidays to build calendars.
+func pharmacyColumns(fields api.PharmacyFields, required ...string) string {
+	if fields.Fields == "" {
+		return "pharmacies.*"
+	}
+	columns := []string{"pharmacies.id"}
+	for _, column := range append(splitList(fields.Fields), required...) {
+		if column != "id" && !containsString(columns, "pharmacies."+column) {
+			columns = append(columns, "pharmacies."+column)
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// preloadIncluded loads the associations in fields.Include into pharmacies
+// with one query per association, however many pharmacies there are.
+// Associations already read with the pharmacies are passed as loaded.
+func preloadIncluded(db *gorm.DB, fields api.PharmacyFields, pharmacies []*global.Pharmacy, loaded ...string) error {
+	var preloads []string
+	for _, include := range splitList(fields.Include) {
+		if association := pharmacyAssociations[include]; !containsString(loaded, association) {
+			preloads = append(preloads, association)
+		}
+	}
+	if len(preloads) == 0 || len(pharmacies) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(pharmacies))
+	for i, pharmacy := range pharmacies {
+		ids[i] = pharmacy.ID
+	}
+	query := db.Select("id").Where("id IN ?", ids)
+	for _, association := range preloads {
+		query = query.Preload(association, func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		})
+	}
+	var rows []global.Pharmacy
+	if err := query.Find(&rows).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uint]global.Pharmacy, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+	for _, pharmacy := range pharmacies {
+		row := byID[pharmacy.ID]
+		for _, association := range preloads {
+			switch association {
+			case "Masks":
+				pharmacy.Masks = row.Masks
+			case "OpeningHours":
+				pharmacy.OpeningHours = row.OpeningHours
+			}
+		}
+	}
+	return nil
+}
+
+// availablePharmacies is the pharmacy of each availability, to load associations into
+func availablePharmacies(available []api.PharmacyAvailability) []*global.Pharmacy {
+	pharmacies := make([]*global.Pharmacy, len(available))
+	for i := range available {
+		pharmacies[i] = &available[i].Pharmacy
+	}
+	return pharmacies
+}
+
+// countedPharmacies is the pharmacy of each mask count result, to load associations into
+func countedPharmacies(results []api.PharmacyWithCount) []*global.Pharmacy {
+	pharmacies := make([]*global.Pharmacy, len(results))
+	for i := range results {
+		pharmacies[i] = &results[i].Pharmacy
+	}
+	return pharmacies
+}
+
+// writePharmacies writes response with only the pharmacy fields in
+// fields.Fields and the associations in fields.Include, in each pharmacy
+// under "pharmacies" or "pharmacy". Without either it is written as is.
+func writePharmacies(c *gin.Context, status int, fields api.PharmacyFields, response interface{}) {
+	projected, err := projectPharmacies(response, fields)
+	if err != nil {
+		c.JSON(status, response)
+		return
+	}
+	c.JSON(status, projected)
+}
+
+// projectPharmacies is response with the pharmacy keys not asked for by
+// fields removed
+func projectPharmacies(response interface{}, fields api.PharmacyFields) (interface{}, error) {
+	if fields.Fields == "" && fields.Include == "" {
+		return response, nil
+	}
+
+	// Keys of the pharmacy itself, others such as open_now or mask_count stay
+	drop := make(map[string]bool)
+	if fields.Fields != "" {
+		for _, key := range api.PharmacyFieldKeys {
+			drop[key] = true
+		}
+		drop[api.PharmacyFieldKeys["id"]] = false
+		for _, field := range splitList(fields.Fields) {
+			drop[api.PharmacyFieldKeys[field]] = false
+		}
+	}
+	if fields.Include != "" {
+		for _, key := range api.PharmacyIncludeKeys {
+			drop[key] = true
+		}
+		for _, include := range splitList(fields.Include) {
+			drop[api.PharmacyIncludeKeys[include]] = false
+		}
+	}
+
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	prune := func(value interface{}) {
+		if pharmacy, ok := value.(map[string]interface{}); ok {
+			for key := range pharmacy {
+				if drop[key] {
+					delete(pharmacy, key)
+				}
+			}
+		}
+	}
+	if pharmacies, ok := document["pharmacies"].([]interface{}); ok {
+		for _, pharmacy := range pharmacies {
+			prune(pharmacy)
+		}
+	}
+	prune(document["pharmacy"])
+	return document, nil
+}
+
+// containsString reports whether items contains item
+func containsString(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"PhantomBE/global"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	items := splitList(" Masks, opening_hours,,masks ")
+	if !reflect.DeepEqual(items, []string{"masks", "opening_hours"}) {
+		t.Errorf("Expected [masks opening_hours], got %v", items)
+	}
+}
+
+func TestPharmacyColumns(t *testing.T) {
+	tests := []struct {
+		fields   api.PharmacyFields
+		required []string
+		expected string
+	}{
+		{api.PharmacyFields{}, nil, "pharmacies.*"},
+		{api.PharmacyFields{Fields: "name,cash_balance"}, nil, "pharmacies.id, pharmacies.name, pharmacies.cash_balance"},
+		{api.PharmacyFields{Fields: "id,name"}, []string{"observes_holidays"}, "pharmacies.id, pharmacies.name, pharmacies.observes_holidays"},
+		{api.PharmacyFields{Fields: "observes_holidays"}, []string{"observes_holidays"}, "pharmacies.id, pharmacies.observes_holidays"},
+	}
+	for _, test := range tests {
+		if columns := pharmacyColumns(test.fields, test.required...); columns != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, columns)
+		}
+	}
+}
+
+func TestProjectPharmacies(t *testing.T) {
+	response := api.OpeningSoonResponse{
+		Minutes: 30,
+		Pharmacies: []api.PharmacyAvailability{{
+			Pharmacy: global.Pharmacy{
+				ID:               1,
+				Name:             "DFW Wellness",
+				CashBalance:      328.41,
+				ObservesHolidays: true,
+				OpeningHours:     []global.OpeningHour{{ID: 3}},
+			},
+		}},
+		Count: 1,
+	}
+	tests := []struct {
+		fields   api.PharmacyFields
+		expected []string
+	}{
+		{api.PharmacyFields{Fields: "name"}, []string{"ID", "masks", "name", "open_now", "openingHours"}},
+		{api.PharmacyFields{Include: "masks"}, []string{"ID", "acceptsOrdersAnytime", "cashBalance", "masks", "name", "observesHolidays", "open_now"}},
+		{api.PharmacyFields{Fields: "cash_balance", Include: "opening_hours"}, []string{"ID", "cashBalance", "open_now", "openingHours"}},
+	}
+	for _, test := range tests {
+		projected, err := projectPharmacies(response, test.fields)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		raw, _ := json.Marshal(projected)
+		var document struct {
+			Minutes    int                      `json:"minutes"`
+			Pharmacies []map[string]interface{} `json:"pharmacies"`
+		}
+		if err := json.Unmarshal(raw, &document); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var keys []string
+		for key := range document.Pharmacies[0] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if document.Minutes != 30 || !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("%+v: expected keys %v, got %d %v", test.fields, test.expected, document.Minutes, keys)
+		}
+	}
+
+	if projected, _ := projectPharmacies(response, api.PharmacyFields{}); !reflect.DeepEqual(projected, response) {
+		t.Errorf("Expected the response unchanged without fields or include")
+	}
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"gorm.io/gorm"
+)
+
+// compositeFilter narrows a pharmacies query to those matching every criterion
+// of req. The masks matching the mask criteria are LEFT JOINed and counted as
+// mask_count, so pharmacies without them count 0 and the count operators
+// apply in HAVING.
+func compositeFilter(req api.CompositeFilterRequest) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if req.Day != "" {
+			db = db.Where("pharmacies.id IN (?)", openPharmacyIDs(db, req.Day, req.Time))
+		}
+		if req.MinCashBalance != nil {
+			db = db.Where("pharmacies.cash_balance >= ?", *req.MinCashBalance)
+		}
+		if req.MaxCashBalance != nil {
+			db = db.Where("pharmacies.cash_balance <= ?", *req.MaxCashBalance)
+		}
+
+		joinClause, joinArgs := matchingMasksJoin(req)
+		db = db.Select(pharmacyColumns(req.PharmacyFields)+", COUNT(masks.id) AS mask_count").
+			Joins(joinClause, joinArgs...).
+			Group("pharmacies.id")
+		if having, args := maskCountCondition(req); having != "" {
+			db = db.Having(having, args...)
+		}
+		return db
+	}
+}
+
+// matchingMasksJoin is the LEFT JOIN of the masks matching the price range and
+// attributes of req
+func matchingMasksJoin(req api.CompositeFilterRequest) (string, []interface{}) {
+	joinClause := "LEFT JOIN masks ON pharmacies.id = masks.pharmacy_id"
+	var joinArgs []interface{}
+
+	// Compare either the base price or the lowest price reachable through a tier
+	priceExpr := "masks.price"
+	if req.PriceBasis == "best_tier" {
+		priceExpr = bestTierPriceExpr
+	}
+	if req.MinPrice > 0 {
+		joinClause += " AND " + priceExpr + " >= ?"
+		joinArgs = append(joinArgs, req.MinPrice)
+	}
+	if req.MaxPrice > 0 {
+		joinClause += " AND " + priceExpr + " <= ?"
+		joinArgs = append(joinArgs, req.MaxPrice)
+	}
+	if conditions, args := maskAttributeConditions(req.MaskAttributeFilter); conditions != "" {
+		joinClause += " AND " + conditions
+		joinArgs = append(joinArgs, args...)
+	}
+	return joinClause, joinArgs
+}
+
+// maskCountCondition is the HAVING condition on the number of matching masks.
+// Without a count operator, mask criteria require at least one matching mask.
+func maskCountCondition(req api.CompositeFilterRequest) (string, []interface{}) {
+	if req.CountOperator == "" || req.Count == nil {
+		if hasMaskCriteria(req) {
+			return "COUNT(masks.id) > 0", nil
+		}
+		return "", nil
+	}
+	switch req.CountOperator {
+	case "more":
+		return "COUNT(masks.id) > ?", []interface{}{*req.Count}
+	case "less":
+		return "COUNT(masks.id) < ?", []interface{}{*req.Count}
+	case "between":
+		return "COUNT(masks.id) BETWEEN ? AND ?", []interface{}{*req.Count, *req.CountMax}
+	default:
+		return "COUNT(masks.id) = ?", []interface{}{*req.Count}
+	}
+}
+
+// hasMaskCriteria reports whether req narrows which masks match
+func hasMaskCriteria(req api.CompositeFilterRequest) bool {
+	return req.MinPrice > 0 || req.MaxPrice > 0 || req.MaskAttributeFilter != api.MaskAttributeFilter{}
+}
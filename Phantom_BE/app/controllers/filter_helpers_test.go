@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"reflect"
+	"testing"
+)
+
+func TestMaskCountCondition(t *testing.T) {
+	two, five := 2, 5
+	tests := []struct {
+		req      api.CompositeFilterRequest
+		expected string
+		args     []interface{}
+	}{
+		{api.CompositeFilterRequest{}, "", nil},
+		{api.CompositeFilterRequest{MaskAttributeFilter: api.MaskAttributeFilter{Brand: "MaskT"}}, "COUNT(masks.id) > 0", nil},
+		{api.CompositeFilterRequest{MaxPrice: 10}, "COUNT(masks.id) > 0", nil},
+		{api.CompositeFilterRequest{CountOperator: "more", Count: &two}, "COUNT(masks.id) > ?", []interface{}{2}},
+		{api.CompositeFilterRequest{CountOperator: "less", Count: &two}, "COUNT(masks.id) < ?", []interface{}{2}},
+		{api.CompositeFilterRequest{CountOperator: "equal", Count: &two}, "COUNT(masks.id) = ?", []interface{}{2}},
+		{api.CompositeFilterRequest{CountOperator: "between", Count: &two, CountMax: &five}, "COUNT(masks.id) BETWEEN ? AND ?", []interface{}{2, 5}},
+	}
+	for _, test := range tests {
+		condition, args := maskCountCondition(test.req)
+		if condition != test.expected || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Expected %q %v, got %q %v", test.expected, test.args, condition, args)
+		}
+	}
+}
+
+func TestMatchingMasksJoin(t *testing.T) {
+	req := api.CompositeFilterRequest{
+		MinPrice:            5,
+		MaskAttributeFilter: api.MaskAttributeFilter{Color: "Blue"},
+	}
+	joinClause, args := matchingMasksJoin(req)
+	expected := "LEFT JOIN masks ON pharmacies.id = masks.pharmacy_id AND masks.price >= ? AND masks.color = LOWER(?)"
+	if joinClause != expected || !reflect.DeepEqual(args, []interface{}{5.0, "Blue"}) {
+		t.Errorf("Expected %q [5 Blue], got %q %v", expected, joinClause, args)
+	}
+}
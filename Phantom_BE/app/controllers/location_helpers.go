@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"PhantomBE/app/geo"
+	"database/sql"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// distanceKmExpr is the haversine distance in km from the point @lat, @lng
+// to a pharmacy, matching geo.DistanceKm
+const distanceKmExpr = "2 * 6371.0 * ASIN(SQRT(LEAST(1, " +
+	"POWER(SIN(RADIANS(pharmacies.latitude - @lat) / 2), 2) + " +
+	"COS(RADIANS(@lat)) * COS(RADIANS(pharmacies.latitude)) * POWER(SIN(RADIANS(pharmacies.longitude - @lng) / 2), 2))))"
+
+// nearbyCursor is the position after the last pharmacy of a nearby page
+type nearbyCursor struct {
+	DistanceKm float64 `json:"distance_km"`
+	ID         uint    `json:"id"`
+}
+
+// pharmaciesNear is a query of the IDs and distances of the located pharmacies
+// within req.RadiusKm of the requested point that sell the requested product.
+// The bounding box lets the location index narrow the rows before distances
+// are computed.
+func pharmaciesNear(db *gorm.DB, req api.NearbyPharmaciesRequest) *gorm.DB {
+	lat, lng := *req.Latitude, *req.Longitude
+	box := geo.BoundingBox(lat, lng, req.RadiusKm)
+
+	located := db.Session(&gorm.Session{NewDB: true}).
+		Table("pharmacies").
+		Select("pharmacies.id, "+distanceKmExpr+" AS distance_km", sql.Named("lat", lat), sql.Named("lng", lng)).
+		Where("pharmacies.latitude BETWEEN ? AND ?", box.MinLat, box.MaxLat).
+		Where("pharmacies.longitude BETWEEN ? AND ?", box.MinLng, box.MaxLng)
+	switch {
+	case req.ProductID != 0:
+		located = located.Where("EXISTS (SELECT 1 FROM masks WHERE masks.pharmacy_id = pharmacies.id AND masks.product_id = ?)", req.ProductID)
+	case req.Mask != "":
+		located = located.Where("EXISTS (SELECT 1 FROM masks WHERE masks.pharmacy_id = pharmacies.id AND LOWER(masks.name) = LOWER(?))", strings.TrimSpace(req.Mask))
+	}
+
+	return db.Table("(?) AS nearby", located).
+		Select("id, distance_km").
+		Where("distance_km <= ?", req.RadiusKm)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"PhantomBE/app/api"
+	"errors"
+	"net/http"
+	"time"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoyaltyController struct {
+	db *gorm.DB
+}
+
+func NewLoyaltyController(db *gorm.DB) *LoyaltyController {
+	return &LoyaltyController{db: db}
+}
+
+// 1. Loyalty points balance of a user with the most recent ledger entries
+// POST /api/v1/loyalty/balance
+func (lc *LoyaltyController) GetPointsBalance(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.PointsBalanceRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	// Set default limit if not provided or zero
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+
+	var response api.PointsBalanceResponse
+	// Expiring points writes to the ledger, so read the balance in the same transaction
+	err := lc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user global.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, req.UserID).Error; err != nil {
+			return err
+		}
+
+		expired, err := expireLoyaltyPoints(tx, &user, time.Now())
+		if err != nil {
+			return err
+		}
+		if expired > 0 {
+			if err := tx.Model(&user).Update("loyalty_points", user.LoyaltyPoints).Error; err != nil {
+				return err
+			}
+		}
+
+		var ledger []global.LoyaltyPointEntry
+		if err := tx.Where("user_id = ?", user.ID).
+			Order("created_at DESC, id DESC").
+			Limit(req.Limit).
+			Find(&ledger).Error; err != nil {
+			return err
+		}
+
+		var next []global.LoyaltyPointEntry
+		if err := tx.Where("user_id = ? AND type IN ? AND remaining > 0 AND expires_at IS NOT NULL", user.ID, spendableEntryTypes).
+			Order("expires_at ASC").
+			Limit(1).
+			Find(&next).Error; err != nil {
+			return err
+		}
+
+		response = api.PointsBalanceResponse{
+			UserID:      user.ID,
+			UserName:    user.Name,
+			Points:      user.LoyaltyPoints,
+			Value:       pointsValue(user.LoyaltyPoints),
+			ExpiredNow:  expired,
+			Ledger:      ledger,
+			LedgerCount: len(ledger),
+		}
+		if len(next) > 0 {
+			response.NextExpiry = &api.PointsExpiry{
+				Points:    next[0].Remaining,
+				ExpiresAt: *next[0].ExpiresAt,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "User not found",
+				Code:  "USER_NOT_FOUND",
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"fmt"
+	"math"
+	"time"
+	"gorm.io/gorm"
+)
+
+// The helpers below keep user.LoyaltyPoints in step with the ledger entries they
+// write; persisting the user row is left to the caller, which already holds it
+// locked inside the same transaction.
+
+// spendableEntryTypes are the ledger entries whose remaining points make up the balance
+var spendableEntryTypes = []string{"earn", "restore"}
+
+// pointsForAmount is the number of points earned by spending amount
+func pointsForAmount(amount float64) int {
+	if amount <= 0 || global.LoyaltyPointsPerDollar <= 0 {
+		return 0
+	}
+	return int(math.Floor(amount*global.LoyaltyPointsPerDollar + 1e-9))
+}
+
+// pointsValue is the discount granted for redeeming points
+func pointsValue(points int) float64 {
+	return roundCurrency(float64(points) * global.LoyaltyPointValue)
+}
+
+// redeemablePoints caps the requested points by the user balance and by the
+// amount they may discount.
+func redeemablePoints(requested, balance int, amount float64) int {
+	points := requested
+	if points > balance {
+		points = balance
+	}
+	if global.LoyaltyPointValue <= 0 {
+		return 0
+	}
+	if maxPoints := int(math.Floor(amount/global.LoyaltyPointValue + 1e-9)); points > maxPoints {
+		points = maxPoints
+	}
+	if points < 0 {
+		return 0
+	}
+	return points
+}
+
+// expireLoyaltyPoints writes off the unspent points of earn and restore entries
+// past their expiry date and returns how many points expired.
+func expireLoyaltyPoints(tx *gorm.DB, user *global.User, now time.Time) (int, error) {
+	var entries []global.LoyaltyPointEntry
+	if err := tx.Where("user_id = ? AND type IN ? AND remaining > 0 AND expires_at <= ?", user.ID, spendableEntryTypes, now).
+		Find(&entries).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, entry := range entries {
+		expired += entry.Remaining
+		if err := tx.Model(&entry).Update("remaining", 0).Error; err != nil {
+			return 0, err
+		}
+	}
+	if expired == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Create(&global.LoyaltyPointEntry{
+		UserID: user.ID,
+		Type:   "expire",
+		Points: -expired,
+	}).Error; err != nil {
+		return 0, err
+	}
+	user.LoyaltyPoints = max(user.LoyaltyPoints-expired, 0)
+	return expired, nil
+}
+
+// earnLoyaltyPoints credits points to the user and returns the new earn entry
+func earnLoyaltyPoints(tx *gorm.DB, user *global.User, points int, now time.Time) (*global.LoyaltyPointEntry, error) {
+	return creditLoyaltyPoints(tx, user, "earn", points, now)
+}
+
+// restoreLoyaltyPoints gives back points redeemed on refunded purchases. They
+// can be spent again and expire like newly earned points.
+func restoreLoyaltyPoints(tx *gorm.DB, user *global.User, points int, now time.Time) (int, error) {
+	if _, err := creditLoyaltyPoints(tx, user, "restore", points, now); err != nil {
+		return 0, err
+	}
+	return max(points, 0), nil
+}
+
+// creditLoyaltyPoints adds a spendable entry of entryType to the ledger
+func creditLoyaltyPoints(tx *gorm.DB, user *global.User, entryType string, points int, now time.Time) (*global.LoyaltyPointEntry, error) {
+	if points <= 0 {
+		return nil, nil
+	}
+	entry := global.LoyaltyPointEntry{
+		UserID:    user.ID,
+		Type:      entryType,
+		Points:    points,
+		Remaining: points,
+	}
+	if global.LoyaltyPointsExpiryDays > 0 {
+		expiresAt := now.AddDate(0, 0, global.LoyaltyPointsExpiryDays)
+		entry.ExpiresAt = &expiresAt
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	user.LoyaltyPoints += points
+	return &entry, nil
+}
+
+// redeemLoyaltyPoints spends points from the entries expiring soonest
+func redeemLoyaltyPoints(tx *gorm.DB, user *global.User, points int) error {
+	if points <= 0 {
+		return nil
+	}
+	if _, err := consumeLoyaltyPoints(tx, user.ID, points, nil); err != nil {
+		return err
+	}
+	if err := tx.Create(&global.LoyaltyPointEntry{
+		UserID: user.ID,
+		Type:   "redeem",
+		Points: -points,
+	}).Error; err != nil {
+		return err
+	}
+	user.LoyaltyPoints -= points
+	return nil
+}
+
+// reverseLoyaltyPoints takes back points earned by refunded purchases, keyed by
+// the earn entry that credited them. Points already spent are recovered from
+// the rest of the balance; the balance never goes below zero. It returns the
+// number of points reversed.
+func reverseLoyaltyPoints(tx *gorm.DB, user *global.User, earned map[uint]int) (int, error) {
+	reversed := 0
+	for entryID, points := range earned {
+		points = min(points, user.LoyaltyPoints-reversed)
+		if points <= 0 {
+			continue
+		}
+		taken, err := consumeLoyaltyPoints(tx, user.ID, points, &entryID)
+		if err != nil {
+			return 0, err
+		}
+		reversed += taken
+	}
+	if reversed == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Create(&global.LoyaltyPointEntry{
+		UserID: user.ID,
+		Type:   "reversal",
+		Points: -reversed,
+	}).Error; err != nil {
+		return 0, err
+	}
+	user.LoyaltyPoints -= reversed
+	return reversed, nil
+}
+
+// consumeLoyaltyPoints lowers the remaining points of spendable entries, starting with
+// first (if given) and then by soonest expiry, and returns how many were taken.
+func consumeLoyaltyPoints(tx *gorm.DB, userID uint, points int, first *uint) (int, error) {
+	var entries []global.LoyaltyPointEntry
+	order := "expires_at ASC NULLS LAST, id ASC"
+	if first != nil {
+		order = fmt.Sprintf("id = %d DESC, %s", *first, order)
+	}
+	if err := tx.Where("user_id = ? AND type IN ? AND remaining > 0", userID, spendableEntryTypes).
+		Order(order).Find(&entries).Error; err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for _, entry := range entries {
+		if taken == points {
+			break
+		}
+		use := min(entry.Remaining, points-taken)
+		if err := tx.Model(&entry).Update("remaining", entry.Remaining-use).Error; err != nil {
+			return 0, err
+		}
+		taken += use
+	}
+	return taken, nil
+}
+
+// splitPoints spreads points over n purchase records, the last one absorbs the remainder
+func splitPoints(points int, n int) []int {
+	parts := make([]int, n)
+	if n == 0 {
+		return parts
+	}
+	for i := range parts {
+		parts[i] = points / n
+	}
+	parts[n-1] += points % n
+	return parts
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"PhantomBE/global"
+)
+
+func TestLoyaltyPointsConversion(t *testing.T) {
+	defaultRate, defaultValue := global.LoyaltyPointsPerDollar, global.LoyaltyPointValue
+	defer func() { global.LoyaltyPointsPerDollar, global.LoyaltyPointValue = defaultRate, defaultValue }()
+	global.LoyaltyPointsPerDollar = 1
+	global.LoyaltyPointValue = 0.01
+
+	if points := pointsForAmount(137.99); points != 137 {
+		t.Errorf("Expected 137 points, got %d", points)
+	}
+	if value := pointsValue(250); value != 2.5 {
+		t.Errorf("Expected value 2.5, got %v", value)
+	}
+	// Balance caps the request
+	if points := redeemablePoints(500, 300, 100); points != 300 {
+		t.Errorf("Expected 300 redeemable points, got %d", points)
+	}
+	// Amount to pay caps the request
+	if points := redeemablePoints(500, 1000, 1.5); points != 150 {
+		t.Errorf("Expected 150 redeemable points, got %d", points)
+	}
+}
+
+func TestSplitPoints(t *testing.T) {
+	expected := []int{3, 3, 4}
+	if result := splitPoints(10, 3); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
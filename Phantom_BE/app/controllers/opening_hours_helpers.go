@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"PhantomBE/global"
+	"PhantomBE/app/schedule"
+	"strings"
+	"time"
+	"gorm.io/gorm"
+)
+
+// openAt narrows an opening_hours query to the shifts covering day at hhmm.
+// A shift closing earlier than it opens runs past midnight, so it also covers
+// the early hours of the following day.
+func openAt(day, hhmm string) func(*gorm.DB) *gorm.DB {
+	previous := schedule.PreviousDay(day)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"((opening_hours.day_of_week = ? AND ("+
+				"(opening_hours.open_time <= opening_hours.close_time AND ? BETWEEN opening_hours.open_time AND opening_hours.close_time) OR "+
+				"(opening_hours.open_time > opening_hours.close_time AND ? >= opening_hours.open_time))) OR "+
+				"(opening_hours.day_of_week = ? AND opening_hours.open_time > opening_hours.close_time AND ? <= opening_hours.close_time))",
+			day, hhmm, hhmm, previous, hhmm)
+	}
+}
+
+// openPharmacyIDs is a subquery of the pharmacies open on day at hhmm
+func openPharmacyIDs(db *gorm.DB, day, hhmm string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Model(&global.OpeningHour{}).Select("pharmacy_id").Scopes(openAt(day, hhmm))
+}
+
+// withShiftsBetween narrows a pharmacies query to those with weekly hours or
+// with override shifts on the dates around from - to; others are never open
+func withShiftsBetween(from, to time.Time) func(*gorm.DB) *gorm.DB {
+	first, last := schedule.Window(from, to)
+	return func(db *gorm.DB) *gorm.DB {
+		subquery := db.Session(&gorm.Session{NewDB: true})
+		return db.Where("(id IN (?) OR id IN (?))",
+			subquery.Model(&global.OpeningHour{}).Select("pharmacy_id"),
+			subquery.Model(&global.OpeningOverride{}).Select("pharmacy_id").
+				Where("closed = ? AND date BETWEEN ? AND ?", false, first, last))
+	}
+}
+
+// opensBetween narrows a pharmacies query to those with a weekly shift opening
+// between from and to, or with override shifts on one of those dates. Holidays
+// and overrides are left to the calendars, so it may keep a few too many.
+func opensBetween(from, to time.Time) func(*gorm.DB) *gorm.DB {
+	condition, args := shiftsOpeningCondition(from, to)
+	return func(db *gorm.DB) *gorm.DB {
+		subquery := db.Session(&gorm.Session{NewDB: true})
+		return db.Where("(id IN (?) OR id IN (?))",
+			subquery.Model(&global.OpeningHour{}).Select("pharmacy_id").Where(condition, args...),
+			subquery.Model(&global.OpeningOverride{}).Select("pharmacy_id").
+				Where("closed = ? AND date BETWEEN ? AND ?", false, from.Format("2006-01-02"), to.Format("2006-01-02")))
+	}
+}
+
+// shiftsOpeningCondition is the condition on opening_hours for the weekly
+// shifts opening between from and to, one day of the week per date
+func shiftsOpeningCondition(from, to time.Time) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	last := to.Format("2006-01-02")
+	for date := from; ; date = date.AddDate(0, 0, 1) {
+		opens, closes := "00:00", "24:00"
+		if date.Equal(from) {
+			opens = from.Format("15:04")
+		}
+		if date.Format("2006-01-02") == last || date.After(to) {
+			closes = to.Format("15:04")
+		}
+		conditions = append(conditions, "(day_of_week = ? AND open_time BETWEEN ? AND ?)")
+		args = append(args, date.Weekday().String(), opens, closes)
+		if closes != "24:00" {
+			break
+		}
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// loadCalendars builds the calendars of pharmacies, whose OpeningHours must be
+// loaded, with the overrides and observed holidays that matter between from and to
+func loadCalendars(db *gorm.DB, pharmacies []global.Pharmacy, from, to time.Time) (map[uint]schedule.Calendar, error) {
+	calendars := make(map[uint]schedule.Calendar, len(pharmacies))
+	if len(pharmacies) == 0 {
+		return calendars, nil
+	}
+
+	first, last := schedule.Window(from, to)
+	ids := make([]uint, len(pharmacies))
+	observing := false
+	for i, pharmacy := range pharmacies {
+		ids[i] = pharmacy.ID
+		observing = observing || pharmacy.ObservesHolidays
+	}
+
+	var overrides []global.OpeningOverride
+	if err := db.Where("pharmacy_id IN ? AND date BETWEEN ? AND ?", ids, first, last).
+		Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+
+	holidays := make(map[string]string)
+	if observing {
+		var rows []global.Holiday
+		if err := db.Where("date BETWEEN ? AND ?", first, last).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, holiday := range rows {
+			holidays[holiday.Date] = holiday.Name
+		}
+	}
+
+	for _, pharmacy := range pharmacies {
+		calendar := schedule.Calendar{
+			Hours:     pharmacy.OpeningHours,
+			Overrides: make(map[string]global.OpeningOverride),
+		}
+		if pharmacy.ObservesHolidays {
+			calendar.Holidays = holidays
+		}
+		calendars[pharmacy.ID] = calendar
+	}
+	for _, override := range overrides {
+		calendars[override.PharmacyID].Overrides[override.Date] = override
+	}
+	return calendars, nil
+}
+
+// pharmacyCalendar loads the opening hours of the pharmacy and builds its calendar around t
+func pharmacyCalendar(tx *gorm.DB, pharmacy global.Pharmacy, t time.Time) (schedule.Calendar, error) {
+	if err := tx.Where("pharmacy_id = ?", pharmacy.ID).Find(&pharmacy.OpeningHours).Error; err != nil {
+		return schedule.Calendar{}, err
+	}
+	calendars, err := loadCalendars(tx, []global.Pharmacy{pharmacy}, t, t)
+	if err != nil {
+		return schedule.Calendar{}, err
+	}
+	return calendars[pharmacy.ID], nil
+}
+
+// availabilityAt reports whether the pharmacy is open at t by its calendar and
+// when that changes
+func availabilityAt(pharmacy global.Pharmacy, calendar schedule.Calendar, t time.Time) api.PharmacyAvailability {
+	availability := api.PharmacyAvailability{Pharmacy: pharmacy}
+	if closesAt, open := calendar.ClosesAt(t); open {
+		availability.OpenNow = true
+		availability.ClosesAt = &closesAt
+	} else if next, ok := calendar.NextOpening(t); ok {
+		availability.NextOpenAt = &next
+	}
+	return availability
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShiftsOpeningCondition(t *testing.T) {
+	tests := []struct {
+		from, to time.Time
+		expected string
+		args     []interface{}
+	}{
+		{
+			time.Date(2025, 6, 6, 9, 0, 0, 0, time.UTC), time.Date(2025, 6, 6, 9, 30, 0, 0, time.UTC),
+			"(day_of_week = ? AND open_time BETWEEN ? AND ?)",
+			[]interface{}{"Friday", "09:00", "09:30"},
+		},
+		{
+			time.Date(2025, 6, 6, 23, 0, 0, 0, time.UTC), time.Date(2025, 6, 7, 0, 30, 0, 0, time.UTC),
+			"(day_of_week = ? AND open_time BETWEEN ? AND ?) OR (day_of_week = ? AND open_time BETWEEN ? AND ?)",
+			[]interface{}{"Friday", "23:00", "24:00", "Saturday", "00:00", "00:30"},
+		},
+	}
+	for _, test := range tests {
+		condition, args := shiftsOpeningCondition(test.from, test.to)
+		if condition != test.expected || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("Expected %q %v, got %q %v", test.expected, test.args, condition, args)
+		}
+	}
+}
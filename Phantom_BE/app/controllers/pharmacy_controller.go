@@ -2,13 +2,19 @@ package controllers
 import (
 	"PhantomBE/global"
 	"PhantomBE/app/api"
+	"PhantomBE/app/pagination"
+	"PhantomBE/app/schedule"
+	"PhantomBE/app/sorting"
+	"PhantomBE/app/suggest"
 	"PhantomBE/app/validation"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strings"
 	"sort"
 	"net/http"
 	"context"
 	"errors"
+	"math"
 	// "fmt"
 	"time"
 	"github.com/gin-gonic/gin"
@@ -24,14 +30,9 @@ func NewPharmacyController(db *gorm.DB) *PharmacyController {
 }
 
 
-// 1. List all pharmacies open at a specific time and on a day of the week
+// 1. List all pharmacies open at a specific time and on a day of the week, open now, or open during an interval
 // POST /api/v1/pharmacies/open
 func (pc *PharmacyController) GetOpenPharmacies(c *gin.Context) {
-
-	// Add timeout context to prevent hanging requests
-	ctx := c.Request.Context()
-
-
 	var req api.OpenPharmaciesRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		if ve, ok := err.(validator.ValidationErrors); ok {
@@ -50,13 +51,59 @@ func (pc *PharmacyController) GetOpenPharmacies(c *gin.Context) {
 		return
 	}
 
+	pc.listOpenPharmacies(c, req)
+}
+
+// listOpenPharmacies writes one page of the pharmacies open at req.Day and
+// req.Time by their weekly hours, or on a concrete date: at req.Date and
+// req.Time, right now when req.Now is set, or over the interval req.From - req.To
+func (pc *PharmacyController) listOpenPharmacies(c *gin.Context, req api.OpenPharmaciesRequest) {
+	ctx := c.Request.Context()
+
+	// Validated days and times may be abbreviated, 12-hour or in Chinese
+	if req.Day != "" {
+		req.Day, _ = schedule.ParseDay(req.Day)
+	}
+	if req.Time != "" {
+		req.Time, _ = schedule.ParseClock(req.Time)
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	var after idCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
+
+	now := time.Now().In(global.BusinessLocation())
+	switch {
+	case req.Now:
+		pc.listPharmaciesOpenDuring(c, req, now, now, after, pageSize)
+		return
+	case req.Date != "":
+		at, _ := time.ParseInLocation("2006-01-02 15:04", req.Date+" "+req.Time, global.BusinessLocation())
+		pc.listPharmaciesOpenDuring(c, req, at, at, after, pageSize)
+		return
+	case req.From != "":
+		from, _ := time.ParseInLocation("2006-01-02 15:04", req.From, global.BusinessLocation())
+		to, _ := time.ParseInLocation("2006-01-02 15:04", req.To, global.BusinessLocation())
+		pc.listPharmaciesOpenDuring(c, req, from, to, after, pageSize)
+		return
+	}
+
+	at := now
+	if next, ok := schedule.NextAt(req.Day, req.Time, now); ok {
+		at = next
+	}
+
 	var pharmacies []global.Pharmacy
 	
-	err := pc.db.WithContext(ctx).
-        Select("pharmacies.*").
-        Joins("JOIN opening_hours ON pharmacies.id = opening_hours.pharmacy_id").
-        Where("opening_hours.day_of_week = ? AND ? BETWEEN opening_hours.open_time AND opening_hours.close_time", req.Day, req.Time).
-        Limit(1000).
+	db := pc.db.WithContext(ctx)
+	err := db.
+		Select(pharmacyColumns(req.PharmacyFields)).
+		Preload("OpeningHours").
+        Where("id IN (?) AND id > ?", openPharmacyIDs(db, req.Day, req.Time), after.ID).
+        Order("id").
+        Limit(pageSize + 1).
         Find(&pharmacies).Error
 
 	if err != nil {
@@ -71,21 +118,111 @@ func (pc *PharmacyController) GetOpenPharmacies(c *gin.Context) {
         return 
     }
 
+	pharmacies, hasMore := pagination.Trim(pharmacies, pageSize)
+	var last idCursor
+	if len(pharmacies) > 0 {
+		last.ID = pharmacies[len(pharmacies)-1].ID
+	}
+
+	// Weekly queries ignore date-specific overrides
+	available := make([]api.PharmacyAvailability, len(pharmacies))
+	for i, pharmacy := range pharmacies {
+		available[i] = availabilityAt(pharmacy, schedule.Calendar{Hours: pharmacy.OpeningHours}, at)
+	}
+	if err := preloadIncluded(db, req.PharmacyFields, availablePharmacies(available), "OpeningHours"); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
 	response := api.OpenPharmaciesResponse{
-		Pharmacies: pharmacies,
-		Count:      len(pharmacies),
+		At:         at,
+		Pharmacies: available,
+		Count:      len(available),
+		PageInfo:   newPageInfo(pageSize, hasMore, last),
 	}
 	
-	c.JSON(http.StatusOK, response)
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, response)
 }
-// 2. List all masks sold by a given pharmacy, sorted by mask name or price
-// POST /api/v1/pharmacies/masks
-func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
 
-		// Add timeout context to prevent hanging requests
+// listPharmaciesOpenDuring writes one page of the pharmacies open for the
+// entire interval from - to, or at any point of it with mode "any", honoring
+// date-specific overrides and observed holidays
+func (pc *PharmacyController) listPharmaciesOpenDuring(c *gin.Context, req api.OpenPharmaciesRequest, from, to time.Time, after idCursor, pageSize int) {
 	ctx := c.Request.Context()
 
+	// Shifts can chain across days, so the interval is checked against each
+	// calendar, one page of candidates at a time until the page is full
+	db := pc.db.WithContext(ctx)
+	var pharmacies []global.Pharmacy
+	calendars := make(map[uint]schedule.Calendar)
+	for lastID := after.ID; len(pharmacies) <= pageSize; {
+		var candidates []global.Pharmacy
+		if err := db.
+			Select(pharmacyColumns(req.PharmacyFields, "observes_holidays")).
+			Preload("OpeningHours").
+			Where("id > ?", lastID).
+			Scopes(withShiftsBetween(from, to)).
+			Order("id").
+			Limit(pageSize + 1).
+			Find(&candidates).Error; err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		loaded, err := loadCalendars(db, candidates, from, to)
+		if err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+		for _, pharmacy := range candidates {
+			calendar := loaded[pharmacy.ID]
+			open := calendar.OpenThroughout(from, to)
+			if req.Mode == "any" {
+				open = calendar.OpenDuring(from, to)
+			}
+			if open {
+				pharmacies = append(pharmacies, pharmacy)
+				calendars[pharmacy.ID] = calendar
+			}
+			if len(pharmacies) > pageSize {
+				break
+			}
+		}
+		if len(candidates) <= pageSize {
+			break
+		}
+		lastID = candidates[len(candidates)-1].ID
+	}
+
+	pharmacies, hasMore := pagination.Trim(pharmacies, pageSize)
+	var last idCursor
+	if len(pharmacies) > 0 {
+		last.ID = pharmacies[len(pharmacies)-1].ID
+	}
+
+	available := make([]api.PharmacyAvailability, len(pharmacies))
+	for i, pharmacy := range pharmacies {
+		available[i] = availabilityAt(pharmacy, calendars[pharmacy.ID], from)
+	}
+	if err := preloadIncluded(db, req.PharmacyFields, availablePharmacies(available), "OpeningHours"); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, api.OpenPharmaciesResponse{
+		At:         from,
+		Pharmacies: available,
+		Count:      len(available),
+		PageInfo:   newPageInfo(pageSize, hasMore, last),
+	})
+}
 
+// 2. List all masks sold by a given pharmacy, sorted by mask name or price
+// POST /api/v1/pharmacies/masks
+func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
 	var req api.PharmacyMasksRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		if ve, ok := err.(validator.ValidationErrors); ok {
@@ -104,6 +241,13 @@ func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
 		return
 	}
 
+	pc.listPharmacyMasks(c, req)
+}
+
+// listPharmacyMasks writes one page of the masks of req.PharmacyID in the requested order
+func (pc *PharmacyController) listPharmacyMasks(c *gin.Context, req api.PharmacyMasksRequest) {
+	ctx := c.Request.Context()
+
 	// Set defaults
 	if req.Sort == "" {
 		req.Sort = "name"
@@ -112,6 +256,26 @@ func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
 		req.Order = "asc"
 	}
 
+	if !validPricePerUnitRange(c, req.MaskAttributeFilter) {
+		return
+	}
+	// Validated by valid_sort, keys without a direction take req.Order
+	spec, _ := sorting.Parse(req.Sort, req.Order, api.MaskSortKeys)
+
+	// Cursors only continue the sort order they were issued for
+	pageSize := pagination.PageSize(req.PageSize)
+	var after maskCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
+	if req.Cursor != "" && after.Sort != spec.String() {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Cursor does not match the requested sort order",
+			Code:  "INVALID_CURSOR",
+		})
+		return
+	}
+
 	// Check if pharmacy exists
 	var pharmacy global.Pharmacy
 	err := pc.db.WithContext(ctx).First(&pharmacy, req.PharmacyID).Error
@@ -138,12 +302,26 @@ func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
 	}
 	// Query masks
 	var masks []global.Mask
-	orderClause := req.Sort + " " + req.Order
+	// Ties on the sort keys are broken by ID in the direction of the first key
+	orderClause := spec.OrderBy(maskSortColumns, "masks.id")
 	
-	err = pc.db.WithContext(ctx).
-        Where("pharmacy_id = ?", req.PharmacyID).
+	query := pc.db.WithContext(ctx).
+        Scopes(selectMaskSales(spec)).
+        Preload("PriceTiers", func(db *gorm.DB) *gorm.DB {
+            return db.Order("min_quantity")
+        }).
+        Where("pharmacy_id = ?", req.PharmacyID)
+	if conditions, args := maskAttributeConditions(req.MaskAttributeFilter); conditions != "" {
+		query = query.Where(conditions, args...)
+	}
+	if req.Cursor != "" {
+		// The sort keys are whitelisted by the sort spec parser
+		condition, args := spec.After(maskSortColumns, "masks.id", after.values(spec), after.ID)
+		query = query.Where(condition, args...)
+	}
+	err = query.
         Order(orderClause).
-        Limit(1000).
+        Limit(pageSize + 1).
         Find(&masks).Error
 	if err != nil {
         // Handle database errors via middleware
@@ -157,11 +335,18 @@ func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
         return
     }
 
+	masks, hasMore := pagination.Trim(masks, pageSize)
+	last := maskCursor{Sort: spec.String()}
+	if len(masks) > 0 {
+		last = newMaskCursor(spec, masks[len(masks)-1])
+	}
+
 	response := api.PharmacyMasksResponse{
 		PharmacyID  :pharmacy.ID,
 		PharmacyName: pharmacy.Name,
 		Masks:         masks,
 		Count:         len(masks),
+		PageInfo:      newPageInfo(pageSize, hasMore, last),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -170,9 +355,6 @@ func (pc *PharmacyController) GetPharmacyMasks(c *gin.Context) {
 // 3. List all pharmacies with more or less than x mask products within a price range
 // POST /api/v1/pharmacies/filter
 func (pc *PharmacyController) GetPharmaciesByMaskCount(c *gin.Context) {
-	// Add timeout context to prevent hanging requests
-	ctx := c.Request.Context()
-
 	var req api.PharmacyFilterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		if ve, ok := err.(validator.ValidationErrors); ok {
@@ -191,6 +373,13 @@ func (pc *PharmacyController) GetPharmaciesByMaskCount(c *gin.Context) {
 		return
     }
 
+	pc.listPharmaciesByMaskCount(c, req)
+}
+
+// listPharmaciesByMaskCount writes one page of the pharmacies matching the mask count filter
+func (pc *PharmacyController) listPharmaciesByMaskCount(c *gin.Context, req api.PharmacyFilterRequest) {
+	ctx := c.Request.Context()
+
 	if req.MinPrice > req.MaxPrice {
 		c.JSON(http.StatusBadRequest, global.ErrorResponse{
 			Error: "min_price cannot be greater than max_price",
@@ -202,6 +391,15 @@ func (pc *PharmacyController) GetPharmaciesByMaskCount(c *gin.Context) {
 		})
 		return
 	}
+	if !validPricePerUnitRange(c, req.MaskAttributeFilter) {
+		return
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	var after idCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
 
 	var results []api.PharmacyWithCount
 	var havingClause string
@@ -212,13 +410,29 @@ func (pc *PharmacyController) GetPharmaciesByMaskCount(c *gin.Context) {
 		havingClause = "COUNT(masks.id) < ?"
 	}
 
+	// Compare either the base price or the lowest price reachable through a tier
+	priceExpr := "masks.price"
+	if req.PriceBasis == "best_tier" {
+		priceExpr = bestTierPriceExpr
+	}
+
+	// Attribute conditions belong to the join so unmatched pharmacies count 0
+	joinClause := "LEFT JOIN masks ON pharmacies.id = masks.pharmacy_id AND " + priceExpr + " BETWEEN ? AND ?"
+	joinArgs := []interface{}{req.MinPrice, req.MaxPrice}
+	if conditions, args := maskAttributeConditions(req.MaskAttributeFilter); conditions != "" {
+		joinClause += " AND " + conditions
+		joinArgs = append(joinArgs, args...)
+	}
+
 	err := pc.db.WithContext(ctx).
         Table("pharmacies").
-        Select("pharmacies.*, COUNT(masks.id) as mask_count").
-        Joins("LEFT JOIN masks ON pharmacies.id = masks.pharmacy_id AND masks.price BETWEEN ? AND ?", req.MinPrice, req.MaxPrice).
+        Select(pharmacyColumns(req.PharmacyFields) + ", COUNT(masks.id) as mask_count").
+        Joins(joinClause, joinArgs...).
+        Where("pharmacies.id > ?", after.ID).
         Group("pharmacies.id").
         Having(havingClause, req.Count).
-        Limit(1000).
+        Order("pharmacies.id").
+        Limit(pageSize + 1).
         Find(&results).Error
 
 	if err != nil {
@@ -233,11 +447,22 @@ func (pc *PharmacyController) GetPharmaciesByMaskCount(c *gin.Context) {
         return
 	}
 
+	results, hasMore := pagination.Trim(results, pageSize)
+	var last idCursor
+	if len(results) > 0 {
+		last.ID = results[len(results)-1].ID
+	}
+	if err := preloadIncluded(pc.db.WithContext(ctx), req.PharmacyFields, countedPharmacies(results)); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
 	response := api.PharmacyFilterResponse{
 		Pharmacies:results,
 		Count:len(results),
+		PageInfo:newPageInfo(pageSize, hasMore, last),
 	}
-	c.JSON(http.StatusOK, response)
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, response)
 }
 
 // 4. The top x users by total transaction amount of masks within a date range
@@ -269,6 +494,14 @@ func (pc *PharmacyController) GetTopUsers(c *gin.Context) {
 		req.Limit = 10
 	}
 
+	// Pages continue the ranking until the top limit users have been returned
+	pageSize := pagination.PageSize(req.PageSize)
+	var after topUsersCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
+	fetch := max(min(pageSize, req.Limit-after.Rank), 0)
+
 	// Parse dates (validation already ensures correct format)
 	startDate, _ := time.Parse("2006-01-02", req.StartDate)
 	endDate, _ := time.Parse("2006-01-02", req.EndDate)
@@ -284,7 +517,7 @@ func (pc *PharmacyController) GetTopUsers(c *gin.Context) {
 	}
 
 	var topUsers []UserWithTotal
-	err := pc.db.WithContext(ctx).
+	query := pc.db.WithContext(ctx).
 		Table("purchases AS p").
 		Joins("JOIN users u ON u.id = p.user_id").
 		Select(
@@ -292,11 +525,19 @@ func (pc *PharmacyController) GetTopUsers(c *gin.Context) {
 			"u.name AS user_name", 
 			"SUM(p.transaction_amount) AS total_amount",
 			"COUNT(*) AS transaction_count").
-		Where("p.transaction_date BETWEEN ? AND ?", startDate, endDate).
-		Group("u.id, u.name").
-		Order("total_amount DESC").
-		Limit(req.Limit).
-		Scan(&topUsers).Error
+		Where("p.transaction_date BETWEEN ? AND ? AND p.refunded_at IS NULL", startDate, endDate).
+		Group("u.id, u.name")
+	if req.Cursor != "" {
+		query = query.Having("SUM(p.transaction_amount) < ? OR (SUM(p.transaction_amount) = ? AND u.id > ?)",
+			after.TotalAmount, after.TotalAmount, after.UserID)
+	}
+	var err error
+	if fetch > 0 {
+		err = query.
+			Order("total_amount DESC, u.id").
+			Limit(fetch + 1).
+			Scan(&topUsers).Error
+	}
 
 	if err != nil {
 		key := global.DBErrorKey
@@ -308,6 +549,9 @@ func (pc *PharmacyController) GetTopUsers(c *gin.Context) {
 		c.Abort()
 		return
 	}
+	topUsers, hasMore := pagination.Trim(topUsers, fetch)
+	hasMore = hasMore && after.Rank+len(topUsers) < req.Limit
+
 	// Transform results to response format
 	responseUsers := make([]api.UserTransactionSummary, len(topUsers))
 	for i, user := range topUsers {
@@ -322,14 +566,21 @@ func (pc *PharmacyController) GetTopUsers(c *gin.Context) {
 			TotalAmount:      user.TotalAmount,
 			TransactionCount: user.TransactionCount,
 			AverageAmount:    avgAmount,
-			Rank:             i + 1,
+			Rank:             after.Rank + i + 1,
 		}
 	}
+
+	last := after
+	if len(topUsers) > 0 {
+		lastUser := topUsers[len(topUsers)-1]
+		last = topUsersCursor{TotalAmount: lastUser.TotalAmount, UserID: lastUser.UserID, Rank: after.Rank + len(topUsers)}
+	}
 	
 	response := api.TopUsersResponse{
 		TopUsers: responseUsers,
 		Count:    len(responseUsers),
 		Limit: req.Limit,
+		PageInfo: newPageInfo(pageSize, hasMore, last),
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -375,7 +626,7 @@ func (pc *PharmacyController) GetTransactionSummary(c *gin.Context) {
 	err := pc.db.WithContext(ctx).
 		Table("purchases").
 		Select("COUNT(*) as total_masks, SUM(transaction_amount) as total_value, COUNT(*) as transaction_count, AVG(transaction_amount) as average_value").
-		Where("transaction_date BETWEEN ? AND ?", startDate, endDate).
+		Where("transaction_date BETWEEN ? AND ? AND refunded_at IS NULL", startDate, endDate).
 		Scan(&summary).Error
 
 	if err != nil {
@@ -442,66 +693,67 @@ func (pc *PharmacyController) Search(c *gin.Context) {
 	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
 	req.Query = strings.TrimSpace(req.Query)
 
-	var results []api.SearchResult
-
-	// Search pharmacies
-	if req.Type == "pharmacy" || req.Type == "all" {
-		pharmacyResults, err := pc.searchPharmacies(ctx, req.Query)
-		if err != nil {
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
+	pageSize := pagination.PageSize(req.PageSize)
+	var cursor *searchCursor
+	if req.Cursor != "" {
+		cursor = &searchCursor{}
+		if !decodeCursor(c, req.Cursor, cursor) {
 			return
 		}
-		results = append(results, pharmacyResults...)
 	}
 
-	// Search users
-	if req.Type == "user" || req.Type == "all" {
-		userResults, err := pc.searchUsers(ctx, req.Query)
-		if err != nil {
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
-			return
-		}
-		results = append(results, userResults...)
+	if req.Mask != nil && !validPricePerUnitRange(c, req.Mask.MaskAttributeFilter) {
+		return
 	}
 
-	// Search masks
-	if req.Type == "mask" || req.Type == "all" {
-		maskResults, err := pc.searchMasks(ctx, req.Query)
+	// Each table returns its next page in search order, the merged page is cut from those
+	var results []api.SearchResult
+	for _, search := range searchedTypes {
+		if req.Type != search.resultType && req.Type != "all" {
+			continue
+		}
+		found, err := pc.searchTable(ctx, search.resultType, search.table, search.columns, req, cursor, pageSize+1)
 		if err != nil {
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
+			abortWithDBError(c, err)
 			return
 		}
-		results = append(results, maskResults...)
+		results = append(results, found...)
 	}
 
-	// Sort by relevance (higher is better) - using sort.Slice for better performance
+	// Sort by relevance (higher is better), then type and ID for a stable order
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Relevance > results[j].Relevance
+		if results[i].Relevance != results[j].Relevance {
+			return results[i].Relevance > results[j].Relevance
+		}
+		if results[i].Type != results[j].Type {
+			return searchTypeRank[results[i].Type] < searchTypeRank[results[j].Type]
+		}
+		return results[i].ID < results[j].ID
 	})
 
+	results, hasMore := pagination.Trim(results, pageSize)
+	var last searchCursor
+	if len(results) > 0 {
+		lastResult := results[len(results)-1]
+		last = searchCursor{Relevance: lastResult.Relevance, TypeRank: searchTypeRank[lastResult.Type], ID: lastResult.ID}
+	}
+
 	response := api.SearchResponse{
 		Results: results,
 		Count:   len(results),
 		Query:   req.Query,
 		Type:    req.Type,
+		PageInfo: newPageInfo(pageSize, hasMore, last),
+	}
+
+	// Facets count every result, so later pages do not repeat them
+	if cursor == nil {
+		facets, err := pc.searchFacets(ctx, req)
+		if err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+		response.Facets = facets
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -530,194 +782,29 @@ func (pc *PharmacyController) ProcessPurchase(c *gin.Context) {
 		return
 	}
 
-	// Start transaction
-	tx := pc.db.WithContext(ctx).Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Get user with row lock to prevent concurrent modifications
-	var user global.User
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&user, req.UserID).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, global.ErrorResponse{
-				Error: "User not found",
-				Code:  "USER_NOT_FOUND",
-			})
-		} else {
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
-		}
-		return
-	}
-
-	// Get mask
-	var mask global.Mask
-	if err := tx.First(&mask, req.MaskID).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, global.ErrorResponse{
-				Error: "Mask not found",
-				Code:  "MASK_NOT_FOUND",
-			})
-		} else {
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
-		}
-		return
-	}
-
-	// Verify mask belongs to the specified pharmacy
-	if mask.PharmacyID != req.PharmacyID {
-		tx.Rollback()
-		c.JSON(http.StatusBadRequest, global.ErrorResponse{
-			Error: "Mask does not belong to specified pharmacy",
-			Code:  "MASK_PHARMACY_MISMATCH",
-			Details: gin.H{
-				"mask_pharmacy_id": mask.PharmacyID,
-				"requested_pharmacy_id": req.PharmacyID,
-			},
-		})
-		return
-	}
-
-	// Get pharmacy with row lock
-	var pharmacy global.Pharmacy
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&pharmacy, req.PharmacyID).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, global.ErrorResponse{
-				Error: "Pharmacy not found",
-				Code:  "PHARMACY_NOT_FOUND",
+	// The purchase itself runs in one transaction, shared with scheduled subscriptions
+	response, err := executePurchase(ctx, pc.db, purchaseInput{
+		UserID:       req.UserID,
+		PharmacyID:   req.PharmacyID,
+		MaskID:       req.MaskID,
+		Quantity:     req.Quantity,
+		CouponCode:   req.CouponCode,
+		RedeemPoints: req.RedeemPoints,
+	})
+	if err != nil {
+		var perr *purchaseError
+		if errors.As(err, &perr) {
+			c.JSON(perr.Status, global.ErrorResponse{
+				Error: perr.Message,
+				Code:  perr.Code,
+				Details: perr.Details,
 			})
-		} else {
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
-		}
-		return
-	}
-
-	// Calculate total amount
-	totalAmount := mask.Price * float64(req.Quantity)
-	previousBalance := user.CashBalance
-
-	// Check if user has sufficient balance
-	if user.CashBalance < totalAmount {
-		tx.Rollback()
-		c.JSON(http.StatusBadRequest, global.ErrorResponse{
-			Error: "Insufficient balance",
-			Code:  "INSUFFICIENT_BALANCE",
-			Details: gin.H{
-				"required_amount": totalAmount,
-				"current_balance": user.CashBalance,
-				"shortage":        totalAmount - user.CashBalance,
-			},
-		})
-		return
-	}
-
-	// Update user balance
-	user.CashBalance -= totalAmount
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		key := global.DBErrorKey
-		if errors.Is(err, context.DeadlineExceeded) {
-			key = global.DBTimeoutKey
-		}
-		ctx = context.WithValue(ctx, key, err)
-		c.Request = c.Request.WithContext(ctx)
-		c.Abort()
-		return
-	}
-
-	// Update pharmacy balance
-	pharmacy.CashBalance += totalAmount
-	if err := tx.Save(&pharmacy).Error; err != nil {
-		tx.Rollback()
-		key := global.DBErrorKey
-		if errors.Is(err, context.DeadlineExceeded) {
-			key = global.DBTimeoutKey
-		}
-		ctx = context.WithValue(ctx, key, err)
-		c.Request = c.Request.WithContext(ctx)
-		c.Abort()
-		return
-	}
-
-	// Create purchase records (one for each quantity)
-	purchaseIDs := make([]uint, 0, req.Quantity)
-	for i := 0; i < req.Quantity; i++ {
-		purchase := global.Purchase{
-			UserID:            req.UserID,
-			PharmacyName:      pharmacy.Name,
-			MaskName:          mask.Name,
-			TransactionAmount: mask.Price,
-			TransactionDate:   time.Now(),
-		}
-
-		if err := tx.Create(&purchase).Error; err != nil {
-			tx.Rollback()
-			key := global.DBErrorKey
-			if errors.Is(err, context.DeadlineExceeded) {
-				key = global.DBTimeoutKey
-			}
-			ctx = context.WithValue(ctx, key, err)
-			c.Request = c.Request.WithContext(ctx)
-			c.Abort()
 			return
 		}
-		purchaseIDs = append(purchaseIDs, purchase.ID)
-	}
-
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		key := global.DBErrorKey
-		if errors.Is(err, context.DeadlineExceeded) {
-			key = global.DBTimeoutKey
-		}
-		ctx = context.WithValue(ctx, key, err)
-		c.Request = c.Request.WithContext(ctx)
-		c.Abort()
+		abortWithDBError(c, err)
 		return
 	}
-	
-	response := api.PurchaseResponse{
-		Success:     true,
-		Message:     "Purchase completed successfully",
-		PurchaseIDs: purchaseIDs,
-		Details: api.PurchaseDetails{
-			UserID:          req.UserID,
-			UserName:        user.Name,
-			PharmacyID:      req.PharmacyID,
-			PharmacyName:    pharmacy.Name,
-			MaskID:          req.MaskID,
-			MaskName:        mask.Name,
-			UnitPrice:       mask.Price,
-			Quantity:        req.Quantity,
-			TotalAmount:     totalAmount,
-			PreviousBalance: previousBalance,
-			NewBalance:      user.CashBalance,
-		},
-		Timestamp: time.Now(),
-	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -765,4 +852,998 @@ func (pc *PharmacyController) HealthCheck(c *gin.Context) {
 	}
 	
 	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// 9. Platform commission collected from purchases within a date range
+// POST /api/v1/pharmacies/commissions/summary
+func (pc *PharmacyController) GetCommissionSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.CommissionSummaryRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	// Parse dates (validation already ensures correct format)
+	startDate, _ := time.Parse("2006-01-02", req.StartDate)
+	endDate, _ := time.Parse("2006-01-02", req.EndDate)
+
+	// End date should include the entire day
+	endDate = endDate.Add(24*time.Hour - time.Second)
+
+	var pharmacies []api.PharmacyCommissionSummary
+	err := pc.db.WithContext(ctx).
+		Table("purchases").
+		Select(
+			"pharmacy_name",
+			"SUM(transaction_amount) AS gross_amount",
+			"SUM(commission_amount) AS commission_amount",
+			"SUM(transaction_amount - commission_amount) AS pharmacy_amount",
+			"COUNT(*) AS transaction_count").
+		Where("transaction_date BETWEEN ? AND ? AND refunded_at IS NULL", startDate, endDate).
+		Group("pharmacy_name").
+		Order("commission_amount DESC, pharmacy_name").
+		Scan(&pharmacies).Error
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	var account global.PlatformAccount
+	err = pc.db.WithContext(ctx).Where("name = ?", global.PlatformAccountName).Limit(1).Find(&account).Error
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	var summary api.CommissionSummaryData
+	for i := range pharmacies {
+		pharmacies[i].GrossAmount = roundCurrency(pharmacies[i].GrossAmount)
+		pharmacies[i].CommissionAmount = roundCurrency(pharmacies[i].CommissionAmount)
+		pharmacies[i].PharmacyAmount = roundCurrency(pharmacies[i].PharmacyAmount)
+		summary.GrossAmount += pharmacies[i].GrossAmount
+		summary.CommissionAmount += pharmacies[i].CommissionAmount
+		summary.PharmacyAmount += pharmacies[i].PharmacyAmount
+		summary.TransactionCount += pharmacies[i].TransactionCount
+	}
+	summary.GrossAmount = roundCurrency(summary.GrossAmount)
+	summary.CommissionAmount = roundCurrency(summary.CommissionAmount)
+	summary.PharmacyAmount = roundCurrency(summary.PharmacyAmount)
+
+	response := api.CommissionSummaryResponse{
+		Summary:         summary,
+		Pharmacies:      pharmacies,
+		PlatformBalance: account.CashBalance,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 10. Replace the quantity price tiers of a mask
+// POST /api/v1/pharmacies/masks/tiers
+func (pc *PharmacyController) SetMaskPriceTiers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.MaskPriceTiersRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	var mask global.Mask
+	if err := pc.db.WithContext(ctx).First(&mask, req.MaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Mask not found",
+				Code:  "MASK_NOT_FOUND",
+				Details: gin.H{
+					"mask_id": req.MaskID,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	tiers := make([]global.MaskPriceTier, len(req.Tiers))
+	for i, tier := range req.Tiers {
+		tiers[i] = global.MaskPriceTier{
+			MaskID:          mask.ID,
+			MinQuantity:     tier.MinQuantity,
+			DiscountPercent: tier.DiscountPercent,
+		}
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].MinQuantity < tiers[j].MinQuantity
+	})
+
+	// Swap the whole tier set atomically
+	err := pc.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mask_id = ?", mask.ID).Delete(&global.MaskPriceTier{}).Error; err != nil {
+			return err
+		}
+		if len(tiers) == 0 {
+			return nil
+		}
+		return tx.Create(&tiers).Error
+	})
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.MaskPriceTiersResponse{
+		MaskID:    mask.ID,
+		MaskName:  mask.Name,
+		BasePrice: mask.Price,
+		Tiers:     tiers,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 11. Refund purchases, reversing balances, commission, loyalty points and coupon uses
+// POST /api/v1/pharmacies/purchases/refund
+func (pc *PharmacyController) RefundPurchases(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.RefundRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	// Ignore repeated IDs
+	seen := make(map[uint]bool)
+	purchaseIDs := make([]uint, 0, len(req.PurchaseIDs))
+	for _, id := range req.PurchaseIDs {
+		if !seen[id] {
+			seen[id] = true
+			purchaseIDs = append(purchaseIDs, id)
+		}
+	}
+
+	tx := pc.db.WithContext(ctx).Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var user global.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, req.UserID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "User not found",
+				Code:  "USER_NOT_FOUND",
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	// Lock the purchases so a concurrent refund waits and then sees refunded_at
+	var purchases []global.Purchase
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND user_id = ?", purchaseIDs, user.ID).
+		Find(&purchases).Error; err != nil {
+		tx.Rollback()
+		abortWithDBError(c, err)
+		return
+	}
+	if len(purchases) != len(purchaseIDs) {
+		tx.Rollback()
+		found := make(map[uint]bool)
+		for _, purchase := range purchases {
+			found[purchase.ID] = true
+		}
+		missing := []uint{}
+		for _, id := range purchaseIDs {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		c.JSON(http.StatusNotFound, global.ErrorResponse{
+			Error: "Purchase not found",
+			Code:  "PURCHASE_NOT_FOUND",
+			Details: gin.H{
+				"purchase_ids": missing,
+			},
+		})
+		return
+	}
+
+	for _, purchase := range purchases {
+		if purchase.RefundedAt != nil {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, global.ErrorResponse{
+				Error: "Purchase already refunded",
+				Code:  "PURCHASE_ALREADY_REFUNDED",
+				Details: gin.H{
+					"purchase_id": purchase.ID,
+				},
+			})
+			return
+		}
+		if purchase.PharmacyID == 0 {
+			// Imported purchase history is not linked to a pharmacy account
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, global.ErrorResponse{
+				Error: "Purchase cannot be refunded",
+				Code:  "PURCHASE_NOT_REFUNDABLE",
+				Details: gin.H{
+					"purchase_id": purchase.ID,
+				},
+			})
+			return
+		}
+	}
+	refund := sumRefund(purchases)
+
+	// Give back the points spent before taking back the points earned, which
+	// may have been spent since
+	now := time.Now()
+	pointsRestored, err := restoreLoyaltyPoints(tx, &user, refund.RedeemedPoints, now)
+	if err != nil {
+		tx.Rollback()
+		abortWithDBError(c, err)
+		return
+	}
+	pointsReversed, err := reverseLoyaltyPoints(tx, &user, refund.EarnedPoints)
+	if err != nil {
+		tx.Rollback()
+		abortWithDBError(c, err)
+		return
+	}
+
+	user.CashBalance += refund.Amount
+	if err := tx.Save(&user).Error; err != nil {
+		tx.Rollback()
+		abortWithDBError(c, err)
+		return
+	}
+
+	for pharmacyID, amount := range refund.PharmacyAmounts {
+		if err := tx.Model(&global.Pharmacy{}).
+			Where("id = ?", pharmacyID).
+			Update("cash_balance", gorm.Expr("cash_balance - ?", roundCurrency(amount))).Error; err != nil {
+			tx.Rollback()
+			abortWithDBError(c, err)
+			return
+		}
+	}
+
+	if err := creditPlatformAccount(tx, -roundCurrency(refund.Commission)); err != nil {
+		tx.Rollback()
+		abortWithDBError(c, err)
+		return
+	}
+
+	result := tx.Model(&global.Purchase{}).
+		Where("id IN ? AND refunded_at IS NULL", purchaseIDs).
+		Update("refunded_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		abortWithDBError(c, result.Error)
+		return
+	}
+	if result.RowsAffected != int64(len(purchaseIDs)) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, global.ErrorResponse{
+			Error: "Purchase already refunded",
+			Code:  "PURCHASE_ALREADY_REFUNDED",
+			Details: gin.H{
+				"purchase_ids": purchaseIDs,
+			},
+		})
+		return
+	}
+
+	if err := releasePromotionRedemptions(tx, refund.Redemptions); err != nil {
+		tx.Rollback()
+		abortWithDBError(c, err)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.RefundResponse{
+		Success:        true,
+		Message:        "Refund completed successfully",
+		RefundedIDs:    purchaseIDs,
+		RefundAmount:   refund.Amount,
+		PointsRestored: pointsRestored,
+		PointsReversed: pointsReversed,
+		NewBalance:     user.CashBalance,
+		PointsBalance:  user.LoyaltyPoints,
+		Timestamp:      now,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 12. Let a pharmacy accept online orders outside its opening hours
+// POST /api/v1/pharmacies/orders/availability
+func (pc *PharmacyController) SetOrderAvailability(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.OrderAvailabilityRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	var pharmacy global.Pharmacy
+	if err := pc.db.WithContext(ctx).First(&pharmacy, req.PharmacyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Pharmacy not found",
+				Code:  "PHARMACY_NOT_FOUND",
+				Details: gin.H{
+					"pharmacy_id": req.PharmacyID,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	pharmacy.AcceptsOrdersAnytime = *req.AcceptsOrdersAnytime
+	if err := pc.db.WithContext(ctx).Model(&pharmacy).Update("accepts_orders_anytime", pharmacy.AcceptsOrdersAnytime).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.OrderAvailabilityResponse{
+		PharmacyID:           pharmacy.ID,
+		PharmacyName:         pharmacy.Name,
+		AcceptsOrdersAnytime: pharmacy.AcceptsOrdersAnytime,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 13. List pharmacies, filtered either by opening time or by mask count
+// GET /api/v2/pharmacies?day=&time= or ?operator=&count=&min_price=&max_price=
+func (pc *PharmacyController) ListPharmacies(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	openFilter := c.Query("day") != "" || c.Query("date") != "" || c.Query("time") != "" || c.Query("now") != "" ||
+		c.Query("from") != "" || c.Query("to") != ""
+	countFilter := c.Query("operator") != ""
+
+	switch {
+	case openFilter && countFilter:
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Filter by opening time or by mask count, not both",
+			Code:  "INVALID_INPUT",
+		})
+		return
+	case openFilter:
+		var req api.OpenPharmaciesRequest
+		if !bindQuery(c, &req) {
+			return
+		}
+		pc.listOpenPharmacies(c, req)
+		return
+	case countFilter:
+		var req api.PharmacyFilterRequest
+		if !bindQuery(c, &req) {
+			return
+		}
+		pc.listPharmaciesByMaskCount(c, req)
+		return
+	}
+
+	var req api.PharmacyListRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	var after idCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
+
+	db := pc.db.WithContext(ctx)
+	var pharmacies []global.Pharmacy
+	if err := db.
+		Select(pharmacyColumns(req.PharmacyFields)).
+		Where("id > ?", after.ID).
+		Order("id").
+		Limit(pageSize + 1).
+		Find(&pharmacies).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	pharmacies, hasMore := pagination.Trim(pharmacies, pageSize)
+	var last idCursor
+	if len(pharmacies) > 0 {
+		last.ID = pharmacies[len(pharmacies)-1].ID
+	}
+	included := make([]*global.Pharmacy, len(pharmacies))
+	for i := range pharmacies {
+		included[i] = &pharmacies[i]
+	}
+	if err := preloadIncluded(db, req.PharmacyFields, included); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.PharmacyListResponse{
+		Pharmacies: pharmacies,
+		Count:      len(pharmacies),
+		PageInfo:   newPageInfo(pageSize, hasMore, last),
+	}
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, response)
+}
+
+// 14. Get a pharmacy with its opening hours and when it closes or next opens
+// GET /api/v2/pharmacies/:id
+func (pc *PharmacyController) GetPharmacy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req api.PharmacyFields
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	var pharmacy global.Pharmacy
+	err := pc.db.WithContext(ctx).
+		Select(pharmacyColumns(req, "observes_holidays")).
+		Preload("OpeningHours", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		First(&pharmacy, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Pharmacy not found",
+				Code:  "PHARMACY_NOT_FOUND",
+				Details: gin.H{
+					"pharmacy_id": id,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	now := time.Now().In(global.BusinessLocation())
+	calendars, err := loadCalendars(pc.db.WithContext(ctx), []global.Pharmacy{pharmacy}, now, now)
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.PharmacyResponse{
+		Pharmacy: availabilityAt(pharmacy, calendars[pharmacy.ID], now),
+	}
+	if err := preloadIncluded(pc.db.WithContext(ctx), req, []*global.Pharmacy{&response.Pharmacy.Pharmacy}, "OpeningHours"); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+	writePharmacies(c, http.StatusOK, req, response)
+}
+
+// 15. List the masks of a pharmacy, sorted by mask name or price
+// GET /api/v2/pharmacies/:id/masks?sort=&order=
+func (pc *PharmacyController) ListPharmacyMasks(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := api.PharmacyMasksRequest{PharmacyID: id}
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	pc.listPharmacyMasks(c, req)
+}
+
+// 16. Get a mask with its price tiers
+// GET /api/v2/masks/:id
+func (pc *PharmacyController) GetMask(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var mask global.Mask
+	err := pc.db.WithContext(ctx).
+		Preload("PriceTiers", func(db *gorm.DB) *gorm.DB {
+			return db.Order("min_quantity")
+		}).
+		First(&mask, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Mask not found",
+				Code:  "MASK_NOT_FOUND",
+				Details: gin.H{
+					"mask_id": id,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.MaskResponse{Mask: mask})
+}
+
+// 17. List the pharmacies that are closed now and open within the next N minutes
+// POST /api/v1/pharmacies/opening-soon
+func (pc *PharmacyController) GetOpeningSoon(c *gin.Context) {
+	var req api.OpeningSoonRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	pc.listOpeningSoon(c, req)
+}
+
+// 17. List the pharmacies that are closed now and open within the next N minutes
+// GET /api/v2/pharmacies/opening-soon?minutes=
+func (pc *PharmacyController) ListOpeningSoon(c *gin.Context) {
+	var req api.OpeningSoonRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+	pc.listOpeningSoon(c, req)
+}
+
+// listOpeningSoon writes the pharmacies opening within req.Minutes, soonest first
+func (pc *PharmacyController) listOpeningSoon(c *gin.Context, req api.OpeningSoonRequest) {
+	ctx := c.Request.Context()
+
+	now := time.Now().In(global.BusinessLocation())
+	until := now.Add(time.Duration(req.Minutes) * time.Minute)
+
+	db := pc.db.WithContext(ctx)
+	var pharmacies []global.Pharmacy
+	if err := db.
+		Select(pharmacyColumns(req.PharmacyFields, "observes_holidays")).
+		Preload("OpeningHours").
+		Scopes(opensBetween(now, until)).
+		Order("id").
+		Find(&pharmacies).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	calendars, err := loadCalendars(db, pharmacies, now, until)
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	opening := []api.PharmacyAvailability{}
+	for _, pharmacy := range pharmacies {
+		availability := availabilityAt(pharmacy, calendars[pharmacy.ID], now)
+		if availability.NextOpenAt != nil && !availability.NextOpenAt.After(until) {
+			opening = append(opening, availability)
+		}
+	}
+	sort.SliceStable(opening, func(i, j int) bool {
+		return opening[i].NextOpenAt.Before(*opening[j].NextOpenAt)
+	})
+	if err := preloadIncluded(db, req.PharmacyFields, availablePharmacies(opening), "OpeningHours"); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, api.OpeningSoonResponse{
+		At:         now,
+		Minutes:    req.Minutes,
+		Pharmacies: opening,
+		Count:      len(opening),
+	})
+}
+
+// 18. Get the weekly schedule of a pharmacy, merged by day and rendered as text
+// GET /api/v2/pharmacies/:id/schedule
+func (pc *PharmacyController) GetPharmacySchedule(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var pharmacy global.Pharmacy
+	err := pc.db.WithContext(ctx).Preload("OpeningHours").First(&pharmacy, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Pharmacy not found",
+				Code:  "PHARMACY_NOT_FOUND",
+				Details: gin.H{
+					"pharmacy_id": id,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	week := schedule.Weekly(pharmacy.OpeningHours)
+	response := api.PharmacyScheduleResponse{
+		PharmacyID:   pharmacy.ID,
+		PharmacyName: pharmacy.Name,
+		Schedule:     week,
+		Text:         schedule.Render(week),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 19. Compare the price of a product across the pharmacies selling it, cheapest first
+// POST /api/v1/pharmacies/masks/compare
+func (pc *PharmacyController) ComparePrices(c *gin.Context) {
+	var req api.PriceComparisonRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	pc.comparePrices(c, req)
+}
+
+// 19. Compare the price of a product across the pharmacies selling it, cheapest first
+// GET /api/v2/masks/compare?product_id=|name=
+func (pc *PharmacyController) ListPriceComparison(c *gin.Context) {
+	var req api.PriceComparisonRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+	pc.comparePrices(c, req)
+}
+
+// comparePrices writes the offers of the requested product with the open-now
+// status of their pharmacies and price statistics
+func (pc *PharmacyController) comparePrices(c *gin.Context, req api.PriceComparisonRequest) {
+	ctx := c.Request.Context()
+	db := pc.db.WithContext(ctx)
+
+	// Mask names identify products case-insensitively
+	var product global.Product
+	query := db
+	if req.ProductID != 0 {
+		query = query.Where("id = ?", req.ProductID)
+	} else {
+		query = query.Where("LOWER(name) = LOWER(?)", strings.TrimSpace(req.Name))
+	}
+	if err := query.First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Product not found",
+				Code:  "PRODUCT_NOT_FOUND",
+				Details: gin.H{
+					"product_id": req.ProductID,
+					"name":       req.Name,
+				},
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	if req.Sort == "" {
+		req.Sort = "price"
+	}
+	spec, _ := sorting.Parse(req.Sort, "", api.MaskSortKeys)
+
+	var masks []global.Mask
+	if err := db.
+		Scopes(selectMaskSales(spec)).
+		Where("product_id = ?", product.ID).
+		Order(spec.OrderBy(maskSortColumns, "masks.id")).
+		Find(&masks).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	ids := make([]uint, len(masks))
+	for i, mask := range masks {
+		ids[i] = mask.PharmacyID
+	}
+	var pharmacies []global.Pharmacy
+	if len(ids) > 0 {
+		if err := db.Preload("OpeningHours").Where("id IN ?", ids).Find(&pharmacies).Error; err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+	}
+
+	now := time.Now().In(global.BusinessLocation())
+	calendars, err := loadCalendars(db, pharmacies, now, now)
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+	availability := make(map[uint]api.PharmacyAvailability, len(pharmacies))
+	for _, pharmacy := range pharmacies {
+		availability[pharmacy.ID] = availabilityAt(pharmacy, calendars[pharmacy.ID], now)
+	}
+
+	offers := make([]api.MaskOffer, 0, len(masks))
+	for _, mask := range masks {
+		pharmacy, ok := availability[mask.PharmacyID]
+		if !ok {
+			continue
+		}
+		offers = append(offers, api.MaskOffer{
+			MaskID:       mask.ID,
+			PharmacyID:   pharmacy.ID,
+			PharmacyName: pharmacy.Name,
+			Price:        mask.Price,
+			PricePerUnit: mask.PricePerUnit,
+			Sales:        mask.Sales,
+			OpenNow:      pharmacy.OpenNow,
+			ClosesAt:     pharmacy.ClosesAt,
+			NextOpenAt:   pharmacy.NextOpenAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, api.PriceComparisonResponse{
+		Product: product,
+		At:      now,
+		Offers:  offers,
+		Count:   len(offers),
+		Stats:   summarizeOffers(offers),
+	})
+}
+
+// 20. List the pharmacies matching a combination of opening time, mask, mask count and cash balance criteria
+// POST /api/v1/pharmacies/filter/composite
+func (pc *PharmacyController) FilterPharmacies(c *gin.Context) {
+	var req api.CompositeFilterRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	pc.filterPharmacies(c, req)
+}
+
+// 20. List the pharmacies matching a combination of opening time, mask, mask count and cash balance criteria
+// GET /api/v2/pharmacies/filter
+func (pc *PharmacyController) ListFilteredPharmacies(c *gin.Context) {
+	var req api.CompositeFilterRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+	pc.filterPharmacies(c, req)
+}
+
+// filterPharmacies writes one page of the pharmacies matching req, by ID
+func (pc *PharmacyController) filterPharmacies(c *gin.Context, req api.CompositeFilterRequest) {
+	ctx := c.Request.Context()
+
+	// Validated days and times may be abbreviated, 12-hour or in Chinese
+	if req.Day != "" {
+		req.Day, _ = schedule.ParseDay(req.Day)
+	}
+	if req.Time != "" {
+		req.Time, _ = schedule.ParseClock(req.Time)
+	}
+	if !validPricePerUnitRange(c, req.MaskAttributeFilter) {
+		return
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	var after idCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
+
+	var results []api.PharmacyWithCount
+	err := pc.db.WithContext(ctx).
+		Table("pharmacies").
+		Scopes(compositeFilter(req)).
+		Where("pharmacies.id > ?", after.ID).
+		Order("pharmacies.id").
+		Limit(pageSize + 1).
+		Find(&results).Error
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	results, hasMore := pagination.Trim(results, pageSize)
+	var last idCursor
+	if len(results) > 0 {
+		last.ID = results[len(results)-1].ID
+	}
+	if err := preloadIncluded(pc.db.WithContext(ctx), req.PharmacyFields, countedPharmacies(results)); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, api.PharmacyFilterResponse{
+		Pharmacies: results,
+		Count:      len(results),
+		PageInfo:   newPageInfo(pageSize, hasMore, last),
+	})
+}
+
+// 21. Suggest pharmacy, mask and brand names completing what the user is typing
+// POST /api/v1/pharmacies/suggest
+func (pc *PharmacyController) Suggest(c *gin.Context) {
+	var req api.SuggestRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	pc.suggest(c, req)
+}
+
+// 21. Suggest pharmacy, mask and brand names completing what the user is typing
+// GET /api/v2/suggest?q=
+func (pc *PharmacyController) ListSuggestions(c *gin.Context) {
+	var req api.SuggestRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+	pc.suggest(c, req)
+}
+
+// suggest writes the best completions of req.Query from the in-memory index
+func (pc *PharmacyController) suggest(c *gin.Context, req api.SuggestRequest) {
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+	var types []string
+	if req.Type != "" && req.Type != "all" {
+		types = []string{req.Type}
+	}
+
+	suggestions := suggest.Default.Lookup(req.Query, types, req.Limit)
+	c.JSON(http.StatusOK, api.SuggestResponse{
+		Query:       strings.TrimSpace(req.Query),
+		Suggestions: suggestions,
+		Count:       len(suggestions),
+		IndexedAt:   suggest.Default.BuiltAt(),
+	})
+}
+
+// 22. List the pharmacies within a radius of a point, nearest first
+// POST /api/v1/pharmacies/nearby
+func (pc *PharmacyController) GetNearbyPharmacies(c *gin.Context) {
+	var req api.NearbyPharmaciesRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+	pc.listNearbyPharmacies(c, req)
+}
+
+// 22. List the pharmacies within a radius of a point, nearest first
+// GET /api/v2/pharmacies/nearby?lat=&lng=
+func (pc *PharmacyController) ListNearbyPharmacies(c *gin.Context) {
+	var req api.NearbyPharmaciesRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+	pc.listNearbyPharmacies(c, req)
+}
+
+// listNearbyPharmacies writes one page of the pharmacies near the requested
+// point by distance, then ID, with whether they are open now
+func (pc *PharmacyController) listNearbyPharmacies(c *gin.Context, req api.NearbyPharmaciesRequest) {
+	ctx := c.Request.Context()
+
+	if req.RadiusKm == 0 {
+		req.RadiusKm = 5
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	var after *nearbyCursor
+	if req.Cursor != "" {
+		after = &nearbyCursor{}
+		if !decodeCursor(c, req.Cursor, after) {
+			return
+		}
+	}
+
+	// Open now is decided by the calendars, so every candidate is read when filtering on it
+	db := pc.db.WithContext(ctx)
+	query := pharmaciesNear(db, req).Order("distance_km, id")
+	if after != nil {
+		query = query.Where("(distance_km, id) > (?, ?)", after.DistanceKm, after.ID)
+	}
+	if !req.OpenNow {
+		query = query.Limit(pageSize + 1)
+	}
+	var nearby []struct {
+		ID         uint
+		DistanceKm float64
+	}
+	if err := query.Scan(&nearby).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	ids := make([]uint, len(nearby))
+	for i, row := range nearby {
+		ids[i] = row.ID
+	}
+	var pharmacies []global.Pharmacy
+	if len(ids) > 0 {
+		if err := db.
+			Select(pharmacyColumns(req.PharmacyFields, "observes_holidays")).
+			Preload("OpeningHours").
+			Where("id IN ?", ids).
+			Find(&pharmacies).Error; err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+	}
+	now := time.Now().In(global.BusinessLocation())
+	calendars, err := loadCalendars(db, pharmacies, now, now)
+	if err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+	byID := make(map[uint]global.Pharmacy, len(pharmacies))
+	for _, pharmacy := range pharmacies {
+		byID[pharmacy.ID] = pharmacy
+	}
+
+	results := []api.NearbyPharmacy{}
+	for _, row := range nearby {
+		pharmacy, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		availability := availabilityAt(pharmacy, calendars[pharmacy.ID], now)
+		if req.OpenNow && !availability.OpenNow {
+			continue
+		}
+		results = append(results, api.NearbyPharmacy{
+			PharmacyAvailability: availability,
+			DistanceKm:           math.Round(row.DistanceKm*1000) / 1000,
+		})
+		if len(results) > pageSize {
+			break
+		}
+	}
+
+	results, hasMore := pagination.Trim(results, pageSize)
+	var last nearbyCursor
+	if len(results) > 0 {
+		// The cursor keeps the unrounded distance the keyset compares
+		lastID := results[len(results)-1].ID
+		for _, row := range nearby {
+			if row.ID == lastID {
+				last = nearbyCursor{DistanceKm: row.DistanceKm, ID: row.ID}
+				break
+			}
+		}
+	}
+	included := make([]*global.Pharmacy, len(results))
+	for i := range results {
+		included[i] = &results[i].Pharmacy
+	}
+	if err := preloadIncluded(db, req.PharmacyFields, included, "OpeningHours"); err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	writePharmacies(c, http.StatusOK, req.PharmacyFields, api.NearbyPharmaciesResponse{
+		Latitude:   *req.Latitude,
+		Longitude:  *req.Longitude,
+		RadiusKm:   req.RadiusKm,
+		At:         now,
+		Pharmacies: results,
+		Count:      len(results),
+		PageInfo:   newPageInfo(pageSize, hasMore, last),
+	})
+}
@@ -3,159 +3,346 @@ package controllers
 import(
 	"PhantomBE/global"
 	"PhantomBE/app/api"
-	"strings"
+	"PhantomBE/app/pagination"
+	"PhantomBE/app/sorting"
+	"PhantomBE/app/validation"
 	"context"
-	"fmt"
+	"database/sql"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
+// bindRequest binds the JSON body into req (a pointer to a request DTO) and
+// writes the standard validation error response on failure.
+func bindRequest(c *gin.Context, req interface{}) bool {
+	return handleBindError(c, req, c.ShouldBindJSON(req))
+}
 
-// Helper method to search pharmacies
-func (pc *PharmacyController) searchPharmacies(ctx context.Context, query string) ([]api.SearchResult, error) {
-	var pharmacies []global.Pharmacy
-	orderClause := fmt.Sprintf(
-        "CASE WHEN name ILIKE '%s' THEN 1 WHEN name ILIKE '%s' THEN 2 ELSE 3 END",
-        query,
-        query+"%",
-    )
-	// Use parameterized query to prevent SQL injection
-	err := pc.db.WithContext(ctx).
-		Where("name ILIKE ?", "%"+query+"%").
-		Order(orderClause).
-		Limit(50). // Limit results to prevent memory issues
-		Find(&pharmacies).Error
-	
-	if err != nil {
-		return nil, err
+// bindQuery is bindRequest for query-string parameters
+func bindQuery(c *gin.Context, req interface{}) bool {
+	return handleBindError(c, req, c.ShouldBindQuery(req))
+}
+
+func handleBindError(c *gin.Context, req interface{}, err error) bool {
+	if err == nil {
+		return true
 	}
+	if ve, ok := err.(validator.ValidationErrors); ok {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Invalid input",
+			Code:  "INVALID_INPUT",
+			Details: validation.FormatValidationError(ve, reflect.ValueOf(req).Elem().Interface()),
+		})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, global.ErrorResponse{
+		Error: "Invalid request format",
+		Code:  "INVALID_REQUEST",
+		Details: err.Error(),
+	})
+	return false
+}
 
-	results := make([]api.SearchResult, len(pharmacies))
-	for i, pharmacy := range pharmacies {
-		relevance := calculateRelevance(query, pharmacy.Name)
-		results[i] = api.SearchResult{
-			Type:      "pharmacy",
-			ID:        pharmacy.ID,
-			Name:      pharmacy.Name,
-			Relevance: relevance,
-		}
+// parseIDParam reads a positive numeric path parameter such as ":id"
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Invalid " + name,
+			Code:  "INVALID_ID",
+			Details: gin.H{
+				name: c.Param(name),
+			},
+		})
+		return 0, false
 	}
-	
-	return results, nil
+	return uint(id), true
 }
 
-// Helper method to search users
-func (pc *PharmacyController) searchUsers(ctx context.Context, query string) ([]api.SearchResult, error) {
-	var users []global.User
-	orderClause := fmt.Sprintf(
-        "CASE WHEN name ILIKE '%s' THEN 1 WHEN name ILIKE '%s' THEN 2 ELSE 3 END",
-        query,
-        query+"%",
-    )
-	err := pc.db.WithContext(ctx).
-		Where("name ILIKE ?", "%"+query+"%").
-		Order(orderClause).
-		Limit(50).
-		Find(&users).Error
-	
-	if err != nil {
-		return nil, err
+// parseDateParam reads a "YYYY-MM-DD" path parameter such as ":date"
+func parseDateParam(c *gin.Context, name string) (string, bool) {
+	date := c.Param(name)
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Invalid " + name + ", must be in YYYY-MM-DD format",
+			Code:  "INVALID_DATE",
+			Details: gin.H{
+				name: date,
+			},
+		})
+		return "", false
 	}
+	return date, true
+}
 
-	results := make([]api.SearchResult, len(users))
-	for i, user := range users {
-		relevance := calculateRelevance(query, user.Name)
-		results[i] = api.SearchResult{
-			Type:      "user",
-			ID:        user.ID,
-			Name:      user.Name,
-			Relevance: relevance,
-		}
+// abortWithDBError records a database error in the request context so that
+// DatabaseErrorMiddleware can render it, then aborts the handler chain.
+func abortWithDBError(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	key := global.DBErrorKey
+	if errors.Is(err, context.DeadlineExceeded) {
+		key = global.DBTimeoutKey
 	}
-	
-	return results, nil
+	ctx = context.WithValue(ctx, key, err)
+	c.Request = c.Request.WithContext(ctx)
+	c.Abort()
 }
 
-// Helper method to search masks
-func (pc *PharmacyController) searchMasks(ctx context.Context, query string) ([]api.SearchResult, error) {
-	var masks []global.Mask
-	orderClause := fmt.Sprintf(
-        "CASE WHEN name ILIKE '%s' THEN 1 WHEN name ILIKE '%s' THEN 2 ELSE 3 END",
-        query,
-        query+"%",
-    )
-	err := pc.db.WithContext(ctx).
-		Where("name ILIKE ?", "%"+query+"%").
-		Order(orderClause).
-		Limit(50).
-		Find(&masks).Error
-	
-	if err != nil {
-		return nil, err
+
+// decodeCursor unpacks the request cursor into key, writing the error response
+// when it is malformed. An empty cursor leaves key untouched.
+func decodeCursor(c *gin.Context, cursor string, key interface{}) bool {
+	if cursor == "" {
+		return true
+	}
+	if err := pagination.Decode(cursor, key); err != nil {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Invalid cursor",
+			Code:  "INVALID_CURSOR",
+		})
+		return false
+	}
+	return true
+}
+
+// newPageInfo describes a page, pointing the next cursor at lastKey when there is more
+func newPageInfo(pageSize int, hasMore bool, lastKey interface{}) api.PageInfo {
+	info := api.PageInfo{PageSize: pageSize, HasMore: hasMore}
+	if hasMore {
+		info.NextCursor = pagination.Encode(lastKey)
 	}
+	return info
+}
 
-	results := make([]api.SearchResult, len(masks))
-	for i, mask := range masks {
-		relevance := calculateRelevance(query, mask.Name)
-		results[i] = api.SearchResult{
-			Type:       "mask",
-			ID:         mask.ID,
-			Name:       mask.Name,
-			Price:      &mask.Price,
-			PharmacyID: &mask.PharmacyID,
-			Relevance:  relevance,
+// idCursor is the position after the last item of a page sorted by ID
+type idCursor struct {
+	ID uint `json:"id"`
+}
+
+// maskSalesExpr counts the purchases of a mask that were not refunded.
+// Imported history has no mask ID and is matched by pharmacy and mask name.
+const maskSalesExpr = "(SELECT COUNT(*) FROM purchases p WHERE p.refunded_at IS NULL AND " +
+	"(p.mask_id = masks.id OR (p.mask_id = 0 AND p.mask_name = masks.name AND " +
+	"p.pharmacy_name = (SELECT ph.name FROM pharmacies ph WHERE ph.id = masks.pharmacy_id))))"
+
+// maskSortColumns maps the keys of api.MaskSortKeys to their SQL expressions
+var maskSortColumns = map[string]string{
+	"name":           "masks.name",
+	"price":          "masks.price",
+	"brand":          "masks.brand",
+	"color":          "masks.color",
+	"pack_size":      "masks.pack_size",
+	"price_per_unit": "masks.price_per_unit",
+	"popularity":     maskSalesExpr,
+}
+
+// maskCursor is the position after the last mask of a page, valid only for the
+// sort it was issued for
+type maskCursor struct {
+	Sort         string  `json:"sort"` // canonical sort spec
+	Name         string  `json:"name,omitempty"`
+	Price        float64 `json:"price,omitempty"`
+	Brand        string  `json:"brand,omitempty"`
+	Color        string  `json:"color,omitempty"`
+	PackSize     int     `json:"pack_size,omitempty"`
+	PricePerUnit float64 `json:"price_per_unit,omitempty"`
+	Sales        int64   `json:"sales,omitempty"`
+	ID           uint    `json:"id"`
+}
+
+// newMaskCursor returns the position after mask in the given sort
+func newMaskCursor(spec sorting.Spec, mask global.Mask) maskCursor {
+	return maskCursor{
+		Sort:         spec.String(),
+		Name:         mask.Name,
+		Price:        mask.Price,
+		Brand:        mask.Brand,
+		Color:        mask.Color,
+		PackSize:     mask.PackSize,
+		PricePerUnit: mask.PricePerUnit,
+		Sales:        mask.Sales,
+		ID:           mask.ID,
+	}
+}
+
+// values returns the cursor's values of the keys of spec
+func (c maskCursor) values(spec sorting.Spec) []interface{} {
+	values := make([]interface{}, len(spec))
+	for i, key := range spec {
+		switch key.Field {
+		case "price":
+			values[i] = c.Price
+		case "brand":
+			values[i] = c.Brand
+		case "color":
+			values[i] = c.Color
+		case "pack_size":
+			values[i] = c.PackSize
+		case "price_per_unit":
+			values[i] = c.PricePerUnit
+		case "popularity":
+			values[i] = c.Sales
+		default:
+			values[i] = c.Name
 		}
 	}
-	
-	return results, nil
+	return values
 }
 
-// Helper function to calculate relevance score based on search query
-func calculateRelevance(query, target string) float64 {
-	queryLower := strings.ToLower(strings.TrimSpace(query))
-	targetLower := strings.ToLower(strings.TrimSpace(target))
-	
-	if queryLower == "" || targetLower == "" {
-		return 0.0
-	}
-	
-	// Exact match gets highest score
-	if queryLower == targetLower {
-		return 100.0
-	}
-	
-	// Starts with query gets high score
-	if strings.HasPrefix(targetLower, queryLower) {
-		return 90.0
-	}
-	
-	// Ends with query gets good score
-	if strings.HasSuffix(targetLower, queryLower) {
-		return 80.0
-	}
-	
-	// Contains query as whole word gets medium-high score
-	if strings.Contains(" "+targetLower+" ", " "+queryLower+" ") {
-		return 70.0
-	}
-	
-	// Contains query gets medium score
-	if strings.Contains(targetLower, queryLower) {
-		return 60.0
-	}
-	
-	// Calculate similarity based on common characters
-	commonChars := 0
-	for _, char := range queryLower {
-		if strings.ContainsRune(targetLower, char) {
-			commonChars++
+// selectMaskSales adds the sales of each mask to a masks query sorted by popularity
+func selectMaskSales(spec sorting.Spec) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, key := range spec {
+			if key.Field == "popularity" {
+				return db.Select("masks.*, " + maskSalesExpr + " AS sales")
+			}
 		}
+		return db
+	}
+}
+
+// maskAttributeConditions returns the SQL conditions on the masks table for
+// the attributes set in filter, joined with AND. Brand and color match
+// case-insensitively.
+func maskAttributeConditions(filter api.MaskAttributeFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.Brand != "" {
+		conditions = append(conditions, "LOWER(masks.brand) = LOWER(?)")
+		args = append(args, strings.TrimSpace(filter.Brand))
+	}
+	if filter.Color != "" {
+		conditions = append(conditions, "masks.color = LOWER(?)")
+		args = append(args, strings.TrimSpace(filter.Color))
+	}
+	if filter.PackSize > 0 {
+		conditions = append(conditions, "masks.pack_size = ?")
+		args = append(args, filter.PackSize)
 	}
-	
-	if commonChars > 0 {
-		return float64(commonChars) / float64(len(queryLower)) * 40.0
+	if filter.MinPricePerUnit > 0 {
+		conditions = append(conditions, "masks.price_per_unit >= ?")
+		args = append(args, filter.MinPricePerUnit)
 	}
-	
-	return 0.0
+	if filter.MaxPricePerUnit > 0 {
+		conditions = append(conditions, "masks.price_per_unit <= ?")
+		args = append(args, filter.MaxPricePerUnit)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// validPricePerUnitRange writes a 400 and returns false when the price per
+// unit range of filter is inverted
+func validPricePerUnitRange(c *gin.Context, filter api.MaskAttributeFilter) bool {
+	if filter.MaxPricePerUnit > 0 && filter.MinPricePerUnit > filter.MaxPricePerUnit {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "min_price_per_unit cannot be greater than max_price_per_unit",
+			Code:  "INVALID_PRICE_RANGE",
+			Details: gin.H{
+				"min_price_per_unit": filter.MinPricePerUnit,
+				"max_price_per_unit": filter.MaxPricePerUnit,
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// topUsersCursor is the position after the last user of a top users page
+type topUsersCursor struct {
+	TotalAmount float64 `json:"total_amount"`
+	UserID      uint    `json:"user_id"`
+	Rank        int     `json:"rank"`
+}
+
+// searchCursor is the position after the last result of a search page, which
+// is ordered by relevance (descending), then result type, then ID.
+type searchCursor struct {
+	Relevance float64 `json:"relevance"`
+	TypeRank  int     `json:"type_rank"`
+	ID        uint    `json:"id"`
 }
 
+// searchTypeRank orders result types that tie on relevance
+var searchTypeRank = map[string]int{"pharmacy": 0, "user": 1, "mask": 2}
 
+// searchMatchExpr matches a name containing the query @q, similar to it
+// despite typos (pg_trgm), or containing all its words in any order. All
+// branches are served by the trigram and tsvector indexes on LOWER(name).
+const searchMatchExpr = "(LOWER(name) LIKE '%' || LOWER(@q) || '%' " +
+	"OR LOWER(@q) <% LOWER(name) " +
+	"OR LOWER(name) % LOWER(@q) " +
+	"OR to_tsvector('simple', LOWER(name)) @@ plainto_tsquery('simple', LOWER(@q)))"
 
+// searchRelevanceExpr scores a match from 0 to 100: exact match 100, prefix
+// 90, substring 80, all words in any order 75, otherwise by trigram word
+// similarity (up to 70) or whole-name similarity (up to 60).
+const searchRelevanceExpr = "ROUND(CAST(100 * GREATEST(" +
+	"CASE " +
+	"WHEN LOWER(name) = LOWER(@q) THEN 1.0 " +
+	"WHEN LOWER(name) LIKE LOWER(@q) || '%' THEN 0.9 " +
+	"WHEN LOWER(name) LIKE '%' || LOWER(@q) || '%' THEN 0.8 " +
+	"WHEN to_tsvector('simple', LOWER(name)) @@ plainto_tsquery('simple', LOWER(@q)) THEN 0.75 " +
+	"ELSE 0 END, " +
+	"0.7 * word_similarity(LOWER(@q), LOWER(name)), " +
+	"0.6 * similarity(LOWER(@q), LOWER(name))) AS numeric), 2)"
+
+// Helper method to search one table by name, returning up to limit results
+// after the cursor in search order
+func (pc *PharmacyController) searchTable(ctx context.Context, resultType, table, columns string, req api.SearchRequest, after *searchCursor, limit int) ([]api.SearchResult, error) {
+	db := searchQuery(pc.db.WithContext(ctx), resultType, table, columns, req, after)
+
+	type searchRow struct {
+		ID         uint
+		Name       string
+		Price      *float64
+		PharmacyID *uint
+		Relevance  float64
+	}
+	var rows []searchRow
+	if err := db.Limit(limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]api.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = api.SearchResult{
+			Type:       resultType,
+			ID:         row.ID,
+			Name:       row.Name,
+			Price:      row.Price,      // Only for masks
+			PharmacyID: row.PharmacyID, // Only for masks
+			Relevance:  row.Relevance,
+			Highlights: highlightMatches(row.Name, req.Query),
+		}
+	}
+	return results, nil
+}
+
+// searchQuery is the query of the results of one table after the cursor, in
+// search order. Results of a type ranked before the cursor's must be less
+// relevant, of a type ranked after it may tie.
+func searchQuery(db *gorm.DB, resultType, table, columns string, req api.SearchRequest, after *searchCursor) *gorm.DB {
+	matches := searchMatches(db.Session(&gorm.Session{NewDB: true}), resultType, table, req).
+		Select(columns+", "+searchRelevanceExpr+" AS relevance", sql.Named("q", req.Query))
+
+	db = db.Table("(?) AS matches", matches)
+	if after != nil {
+		rank := searchTypeRank[resultType]
+		switch {
+		case rank > after.TypeRank:
+			db = db.Where("relevance <= ?", after.Relevance)
+		case rank < after.TypeRank:
+			db = db.Where("relevance < ?", after.Relevance)
+		default:
+			db = db.Where("(relevance < ? OR (relevance = ? AND id > ?))", after.Relevance, after.Relevance, after.ID)
+		}
+	}
+	return db.Order("relevance DESC, id")
+}
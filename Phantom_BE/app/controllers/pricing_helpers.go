@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+)
+
+// bestTierPriceExpr is the lowest unit price a mask can reach through its price tiers
+const bestTierPriceExpr = "masks.price * (1 - COALESCE((SELECT MAX(t.discount_percent) FROM mask_price_tiers t WHERE t.mask_id = masks.id), 0) / 100)"
+
+// tieredUnitPrice returns the unit price for buying quantity units of mask and the
+// tier that produced it (nil when the base price applies). When several tiers
+// match, the one with the largest discount wins.
+func tieredUnitPrice(mask global.Mask, quantity int) (float64, *global.MaskPriceTier) {
+	var best *global.MaskPriceTier
+	for i := range mask.PriceTiers {
+		tier := &mask.PriceTiers[i]
+		if quantity < tier.MinQuantity {
+			continue
+		}
+		if best == nil || tier.DiscountPercent > best.DiscountPercent {
+			best = tier
+		}
+	}
+	if best == nil {
+		return mask.Price, nil
+	}
+	return roundCurrency(mask.Price * (1 - best.DiscountPercent/100)), best
+}
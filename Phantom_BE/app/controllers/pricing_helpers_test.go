@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"testing"
+	"PhantomBE/global"
+)
+
+func TestTieredUnitPrice(t *testing.T) {
+	mask := global.Mask{
+		Price: 20,
+		PriceTiers: []global.MaskPriceTier{
+			{MinQuantity: 50, DiscountPercent: 10},
+			{MinQuantity: 100, DiscountPercent: 15},
+		},
+	}
+
+	cases := []struct {
+		quantity int
+		expected float64
+	}{
+		{10, 20},
+		{50, 18},
+		{99, 18},
+		{100, 17},
+	}
+	for _, tc := range cases {
+		if result, _ := tieredUnitPrice(mask, tc.quantity); result != tc.expected {
+			t.Errorf("Quantity %d: expected %v, got %v", tc.quantity, tc.expected, result)
+		}
+	}
+}
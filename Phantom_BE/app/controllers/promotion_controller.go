@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"PhantomBE/app/api"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PromotionController struct {
+	db *gorm.DB
+}
+
+func NewPromotionController(db *gorm.DB) *PromotionController {
+	return &PromotionController{db: db}
+}
+
+// 1. Create a promotion with its coupon code and eligibility rules
+// POST /api/v1/promotions
+func (prc *PromotionController) CreatePromotion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.CreatePromotionRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	promotion := global.Promotion{
+		Code:              strings.ToUpper(req.Code),
+		Description:       req.Description,
+		DiscountType:      req.DiscountType,
+		DiscountValue:     req.DiscountValue,
+		BuyQuantity:       req.BuyQuantity,
+		FreeQuantity:      req.FreeQuantity,
+		UserID:            req.UserID,
+		PharmacyID:        req.PharmacyID,
+		MaskNamePattern:   strings.TrimSpace(req.MaskNamePattern),
+		FirstPurchaseOnly: req.FirstPurchaseOnly,
+		MinQuantity:       req.MinQuantity,
+		MaxUses:           req.MaxUses,
+		MaxUsesPerUser:    req.MaxUsesPerUser,
+		Active:            true,
+	}
+	// Date window is inclusive of the whole end date (validation ensures format)
+	if req.StartDate != "" {
+		startDate, _ := time.Parse("2006-01-02", req.StartDate)
+		promotion.StartsAt = &startDate
+	}
+	if req.EndDate != "" {
+		endDate, _ := time.Parse("2006-01-02", req.EndDate)
+		endDate = endDate.Add(24*time.Hour - time.Second)
+		promotion.EndsAt = &endDate
+	}
+
+	// Reject duplicate codes with a clear error instead of a constraint violation
+	if _, err := findPromotion(prc.db.WithContext(ctx), promotion.Code); err == nil {
+		c.JSON(http.StatusConflict, global.ErrorResponse{
+			Error: "Promotion code already exists",
+			Code:  "PROMOTION_CODE_EXISTS",
+			Details: gin.H{
+				"code": promotion.Code,
+			},
+		})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		abortWithDBError(c, err)
+		return
+	}
+
+	if err := prc.db.WithContext(ctx).Create(&promotion).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, api.PromotionResponse{Promotion: promotion})
+}
+
+// 2. List all promotions, newest first
+// GET /api/v1/promotions
+func (prc *PromotionController) ListPromotions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var promotions []global.Promotion
+	if err := prc.db.WithContext(ctx).Order("created_at DESC").Limit(1000).Find(&promotions).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.PromotionListResponse{
+		Promotions: promotions,
+		Count:      len(promotions),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 3. Preview the discount a coupon code would give on a prospective purchase
+// POST /api/v1/promotions/preview
+func (prc *PromotionController) PreviewPromotion(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.PromotionPreviewRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	db := prc.db.WithContext(ctx)
+
+	promotion, err := findPromotion(db, req.Code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Promotion not found",
+				Code:  "PROMOTION_NOT_FOUND",
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	var mask global.Mask
+	if err := db.Preload("PriceTiers").First(&mask, req.MaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Mask not found",
+				Code:  "MASK_NOT_FOUND",
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+	if mask.PharmacyID != req.PharmacyID {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Mask does not belong to specified pharmacy",
+			Code:  "MASK_PHARMACY_MISMATCH",
+			Details: gin.H{
+				"mask_pharmacy_id": mask.PharmacyID,
+				"requested_pharmacy_id": req.PharmacyID,
+			},
+		})
+		return
+	}
+
+	unitPrice, _ := tieredUnitPrice(mask, req.Quantity)
+	response := api.PromotionPreviewResponse{
+		Code:           promotion.Code,
+		UnitPrice:      unitPrice,
+		Quantity:       req.Quantity,
+		OriginalAmount: roundCurrency(unitPrice * float64(req.Quantity)),
+	}
+
+	discount, err := evaluatePromotion(db, promotion, req.UserID, mask, unitPrice, req.Quantity, time.Now())
+	if err != nil {
+		var perr *promotionError
+		if !errors.As(err, &perr) {
+			abortWithDBError(c, err)
+			return
+		}
+		response.Reason = perr.Code
+	} else {
+		response.Eligible = true
+		response.DiscountAmount = discount
+	}
+	response.FinalAmount = roundCurrency(response.OriginalAmount - response.DiscountAmount)
+
+	c.JSON(http.StatusOK, response)
+}
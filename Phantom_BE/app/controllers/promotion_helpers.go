@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"gorm.io/gorm"
+)
+
+// promotionError explains why a coupon code cannot be applied to a purchase
+type promotionError struct {
+	Code    string
+	Message string
+}
+
+func (e *promotionError) Error() string {
+	return e.Message
+}
+
+// findPromotion loads a promotion by its (case-insensitive) coupon code
+func findPromotion(tx *gorm.DB, code string) (global.Promotion, error) {
+	var promotion global.Promotion
+	err := tx.Where("UPPER(code) = ?", strings.ToUpper(code)).First(&promotion).Error
+	return promotion, err
+}
+
+// evaluatePromotion checks every eligibility rule of the promotion against the
+// prospective purchase of quantity units at unitPrice and returns the discount
+// it grants. Rule violations are reported as *promotionError, anything else is
+// a database error.
+func evaluatePromotion(tx *gorm.DB, promotion global.Promotion, userID uint, mask global.Mask, unitPrice float64, quantity int, now time.Time) (float64, error) {
+	if !promotion.Active {
+		return 0, &promotionError{Code: "PROMOTION_INACTIVE", Message: "Promotion is not active"}
+	}
+	if promotion.StartsAt != nil && now.Before(*promotion.StartsAt) {
+		return 0, &promotionError{Code: "PROMOTION_NOT_STARTED", Message: "Promotion has not started yet"}
+	}
+	if promotion.EndsAt != nil && now.After(*promotion.EndsAt) {
+		return 0, &promotionError{Code: "PROMOTION_EXPIRED", Message: "Promotion has expired"}
+	}
+	if promotion.MaxUses > 0 && promotion.UsedCount >= promotion.MaxUses {
+		return 0, &promotionError{Code: "PROMOTION_USAGE_EXCEEDED", Message: "Promotion has reached its usage limit"}
+	}
+	if promotion.UserID != nil && *promotion.UserID != userID {
+		return 0, &promotionError{Code: "PROMOTION_NOT_ELIGIBLE", Message: "Promotion is not available to this user"}
+	}
+	if promotion.PharmacyID != nil && *promotion.PharmacyID != mask.PharmacyID {
+		return 0, &promotionError{Code: "PROMOTION_NOT_ELIGIBLE", Message: "Promotion is not valid at this pharmacy"}
+	}
+	if promotion.MaskNamePattern != "" && !matchesNamePattern(promotion.MaskNamePattern, mask.Name) {
+		return 0, &promotionError{Code: "PROMOTION_NOT_ELIGIBLE", Message: "Promotion does not apply to this mask"}
+	}
+	if quantity < promotion.MinQuantity {
+		return 0, &promotionError{Code: "PROMOTION_MIN_QUANTITY", Message: "Quantity is below the promotion minimum"}
+	}
+
+	if promotion.MaxUsesPerUser > 0 {
+		var used int64
+		if err := tx.Model(&global.PromotionRedemption{}).
+			Where("promotion_id = ? AND user_id = ?", promotion.ID, userID).
+			Count(&used).Error; err != nil {
+			return 0, err
+		}
+		if used >= int64(promotion.MaxUsesPerUser) {
+			return 0, &promotionError{Code: "PROMOTION_USER_LIMIT_EXCEEDED", Message: "User has already used this promotion"}
+		}
+	}
+
+	if promotion.FirstPurchaseOnly {
+		var purchases int64
+		if err := tx.Model(&global.Purchase{}).Where("user_id = ?", userID).Count(&purchases).Error; err != nil {
+			return 0, err
+		}
+		if purchases > 0 {
+			return 0, &promotionError{Code: "PROMOTION_FIRST_PURCHASE_ONLY", Message: "Promotion is only valid on a first purchase"}
+		}
+	}
+
+	// A code that takes nothing off, e.g. buy_x_get_y below one bundle, is not applied
+	discount := promotionDiscount(promotion, unitPrice, quantity)
+	if discount <= 0 {
+		return 0, &promotionError{Code: "PROMOTION_NOT_ELIGIBLE", Message: "Promotion gives no discount on this purchase"}
+	}
+	return discount, nil
+}
+
+// promotionDiscount computes the discount for quantity units at unitPrice,
+// never exceeding the order subtotal.
+func promotionDiscount(promotion global.Promotion, unitPrice float64, quantity int) float64 {
+	subtotal := unitPrice * float64(quantity)
+	var discount float64
+	switch promotion.DiscountType {
+	case "percentage":
+		discount = subtotal * promotion.DiscountValue / 100
+	case "fixed":
+		discount = promotion.DiscountValue
+	case "buy_x_get_y":
+		// e.g. buy 10 get 1: every 11 units in the order, 1 is free
+		bundle := promotion.BuyQuantity + promotion.FreeQuantity
+		if bundle > 0 {
+			freeUnits := quantity / bundle * promotion.FreeQuantity
+			discount = unitPrice * float64(freeUnits)
+		}
+	}
+	discount = roundCurrency(discount)
+	if discount > subtotal {
+		discount = subtotal
+	}
+	return discount
+}
+
+// redeemPromotion consumes one use of the promotion and records the redemption,
+// filling in its ID.
+// The usage counter is incremented conditionally so concurrent purchases cannot
+// exceed MaxUses. The increment also locks the promotion row until the
+// transaction ends, so the per-user count below sees every earlier redemption
+// and concurrent purchases cannot exceed MaxUsesPerUser either.
+func redeemPromotion(tx *gorm.DB, promotion global.Promotion, redemption *global.PromotionRedemption) error {
+	result := tx.Model(&global.Promotion{}).
+		Where("id = ? AND (max_uses = 0 OR used_count < max_uses)", promotion.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &promotionError{Code: "PROMOTION_USAGE_EXCEEDED", Message: "Promotion has reached its usage limit"}
+	}
+	if promotion.MaxUsesPerUser > 0 {
+		var used int64
+		if err := tx.Model(&global.PromotionRedemption{}).
+			Where("promotion_id = ? AND user_id = ?", promotion.ID, redemption.UserID).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(promotion.MaxUsesPerUser) {
+			return &promotionError{Code: "PROMOTION_USER_LIMIT_EXCEEDED", Message: "User has already used this promotion"}
+		}
+	}
+	redemption.PromotionID = promotion.ID
+	return tx.Create(redemption).Error
+}
+
+// releasePromotionRedemptions gives back the coupon uses of refunded purchases.
+// A use is released once every purchase it discounted has been refunded, so
+// purchases must be marked refunded first.
+func releasePromotionRedemptions(tx *gorm.DB, redemptionIDs []uint) error {
+	for _, id := range redemptionIDs {
+		var outstanding int64
+		if err := tx.Model(&global.Purchase{}).
+			Where("promotion_redemption_id = ? AND refunded_at IS NULL", id).
+			Count(&outstanding).Error; err != nil {
+			return err
+		}
+		if outstanding > 0 {
+			continue
+		}
+
+		var redemption global.PromotionRedemption
+		if err := tx.First(&redemption, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		if err := tx.Delete(&redemption).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&global.Promotion{}).
+			Where("id = ? AND used_count > 0", redemption.PromotionID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesNamePattern matches name against a case-insensitive pattern where "*"
+// stands for any run of characters, e.g. "True Barrier*".
+func matchesNamePattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re, err := regexp.Compile("(?i)^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(strings.TrimSpace(name))
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"PhantomBE/global"
+)
+
+func TestPromotionDiscount(t *testing.T) {
+	cases := []struct {
+		name      string
+		promotion global.Promotion
+		quantity  int
+		expected  float64
+	}{
+		{"Percentage", global.Promotion{DiscountType: "percentage", DiscountValue: 10}, 10, 13.7},
+		{"Fixed", global.Promotion{DiscountType: "fixed", DiscountValue: 5}, 1, 5},
+		{"FixedCappedAtSubtotal", global.Promotion{DiscountType: "fixed", DiscountValue: 50}, 1, 13.7},
+		{"BuyTenGetOne", global.Promotion{DiscountType: "buy_x_get_y", BuyQuantity: 10, FreeQuantity: 1}, 22, 27.4},
+		{"BuyTenGetOneTooFew", global.Promotion{DiscountType: "buy_x_get_y", BuyQuantity: 10, FreeQuantity: 1}, 10, 0},
+	}
+	for _, tc := range cases {
+		if result := promotionDiscount(tc.promotion, 13.7, tc.quantity); result != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, result)
+		}
+	}
+}
+
+func TestEvaluatePromotionWithoutDiscount(t *testing.T) {
+	promotion := global.Promotion{Active: true, DiscountType: "buy_x_get_y", BuyQuantity: 10, FreeQuantity: 1}
+	mask := global.Mask{Name: "True Barrier (green) (3 per pack)"}
+
+	_, err := evaluatePromotion(nil, promotion, 1, mask, 13.7, 10, time.Now())
+	var perr *promotionError
+	if !errors.As(err, &perr) || perr.Code != "PROMOTION_NOT_ELIGIBLE" {
+		t.Errorf("Expected PROMOTION_NOT_ELIGIBLE below one bundle, got %v", err)
+	}
+	if discount, err := evaluatePromotion(nil, promotion, 1, mask, 13.7, 11, time.Now()); err != nil || discount != 13.7 {
+		t.Errorf("Expected 13.7 off one bundle, got %v %v", discount, err)
+	}
+}
+
+func TestMatchesNamePattern(t *testing.T) {
+	if !matchesNamePattern("true barrier*", "True Barrier (green) (3 per pack)") {
+		t.Errorf("Expected brand prefix pattern to match")
+	}
+	if matchesNamePattern("MaskT*", "True Barrier (green) (3 per pack)") {
+		t.Errorf("Expected other brand not to match")
+	}
+	if !matchesNamePattern("*(green)*", "MaskT (green) (10 per pack)") {
+		t.Errorf("Expected infix pattern to match")
+	}
+}
@@ -0,0 +1,333 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"PhantomBE/app/api"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// purchaseInput describes a purchase, whether it comes from the purchase API or
+// from a scheduled subscription.
+type purchaseInput struct {
+	UserID       uint
+	PharmacyID   uint
+	MaskID       uint
+	Quantity     int
+	CouponCode   string
+	RedeemPoints int
+}
+
+// purchaseError is a business rule that rejected the purchase, carrying the HTTP
+// status and error code the purchase API responds with.
+type purchaseError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *purchaseError) Error() string {
+	return e.Message
+}
+
+// executePurchase moves money from the user to the pharmacy (minus commission)
+// and records the purchase in a single transaction. Rejections are returned as
+// *purchaseError; any other error comes from the database.
+func executePurchase(ctx context.Context, db *gorm.DB, in purchaseInput) (*api.PurchaseResponse, error) {
+	var response *api.PurchaseResponse
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Get user with row lock to prevent concurrent modifications
+		var user global.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, in.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &purchaseError{Status: http.StatusNotFound, Code: "USER_NOT_FOUND", Message: "User not found"}
+			}
+			return err
+		}
+
+		// Get mask
+		var mask global.Mask
+		if err := tx.Preload("PriceTiers").First(&mask, in.MaskID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &purchaseError{Status: http.StatusNotFound, Code: "MASK_NOT_FOUND", Message: "Mask not found"}
+			}
+			return err
+		}
+
+		// Verify mask belongs to the specified pharmacy
+		if mask.PharmacyID != in.PharmacyID {
+			return &purchaseError{
+				Status:  http.StatusBadRequest,
+				Code:    "MASK_PHARMACY_MISMATCH",
+				Message: "Mask does not belong to specified pharmacy",
+				Details: gin.H{
+					"mask_pharmacy_id": mask.PharmacyID,
+					"requested_pharmacy_id": in.PharmacyID,
+				},
+			}
+		}
+
+		// Get pharmacy with row lock
+		var pharmacy global.Pharmacy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pharmacy, in.PharmacyID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &purchaseError{Status: http.StatusNotFound, Code: "PHARMACY_NOT_FOUND", Message: "Pharmacy not found"}
+			}
+			return err
+		}
+
+		// Reject orders outside opening hours unless the pharmacy accepts them around the clock
+		now := time.Now()
+		if !pharmacy.AcceptsOrdersAnytime {
+			local := now.In(global.BusinessLocation())
+			calendar, err := pharmacyCalendar(tx, pharmacy, local)
+			if err != nil {
+				return err
+			}
+			if !calendar.IsOpen(local) {
+				var nextOpenAt *time.Time
+				if next, ok := calendar.NextOpening(local); ok {
+					nextOpenAt = &next
+				}
+				return &purchaseError{
+					Status:  http.StatusConflict,
+					Code:    "PHARMACY_CLOSED",
+					Message: "Pharmacy is closed",
+					Details: gin.H{
+						"pharmacy_id":  pharmacy.ID,
+						"next_open_at": nextOpenAt,
+					},
+				}
+			}
+		}
+
+		// Calculate total amount at the price tier matching the quantity
+		unitPrice, tier := tieredUnitPrice(mask, in.Quantity)
+		subtotalAmount := roundCurrency(unitPrice * float64(in.Quantity))
+		previousBalance := user.CashBalance
+
+		// Apply coupon discount, if any
+		var promotion global.Promotion
+		discountAmount := 0.0
+		if in.CouponCode != "" {
+			var err error
+			promotion, err = findPromotion(tx, in.CouponCode)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return &purchaseError{Status: http.StatusNotFound, Code: "PROMOTION_NOT_FOUND", Message: "Promotion not found"}
+				}
+				return err
+			}
+			discountAmount, err = evaluatePromotion(tx, promotion, user.ID, mask, unitPrice, in.Quantity, now)
+			if err != nil {
+				var perr *promotionError
+				if errors.As(err, &perr) {
+					return &purchaseError{
+						Status:  http.StatusBadRequest,
+						Code:    perr.Code,
+						Message: perr.Message,
+						Details: gin.H{
+							"coupon_code": in.CouponCode,
+						},
+					}
+				}
+				return err
+			}
+		}
+
+		// Expire stale loyalty points, then redeem the requested points against what is left to pay
+		if _, err := expireLoyaltyPoints(tx, &user, now); err != nil {
+			return err
+		}
+		if in.RedeemPoints > user.LoyaltyPoints {
+			return &purchaseError{
+				Status:  http.StatusBadRequest,
+				Code:    "INSUFFICIENT_POINTS",
+				Message: "Insufficient loyalty points",
+				Details: gin.H{
+					"requested_points": in.RedeemPoints,
+					"current_points":   user.LoyaltyPoints,
+				},
+			}
+		}
+		pointsRedeemed := redeemablePoints(in.RedeemPoints, user.LoyaltyPoints, subtotalAmount-discountAmount)
+		pointsDiscount := pointsValue(pointsRedeemed)
+		totalAmount := roundCurrency(subtotalAmount - discountAmount - pointsDiscount)
+
+		// Check if user has sufficient balance
+		if user.CashBalance < totalAmount {
+			return &purchaseError{
+				Status:  http.StatusBadRequest,
+				Code:    "INSUFFICIENT_BALANCE",
+				Message: "Insufficient balance",
+				Details: gin.H{
+					"required_amount": totalAmount,
+					"current_balance": user.CashBalance,
+					"shortage":        totalAmount - user.CashBalance,
+				},
+			}
+		}
+
+		// Spend redeemed points and reward the amount actually paid
+		if err := redeemLoyaltyPoints(tx, &user, pointsRedeemed); err != nil {
+			return err
+		}
+		pointsEarned := pointsForAmount(totalAmount)
+		earnEntry, err := earnLoyaltyPoints(tx, &user, pointsEarned, now)
+		if err != nil {
+			return err
+		}
+
+		// Update user balance
+		user.CashBalance -= totalAmount
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		// Deduct the platform commission, the pharmacy receives the remainder
+		fee := calculateCommission(pharmacy, totalAmount)
+		pharmacyAmount := totalAmount - fee.Amount
+		if err := creditPlatformAccount(tx, fee.Amount); err != nil {
+			return err
+		}
+
+		// Update pharmacy balance
+		pharmacy.CashBalance += pharmacyAmount
+		if err := tx.Save(&pharmacy).Error; err != nil {
+			return err
+		}
+
+		// Record the coupon use within the same transaction
+		var redemption global.PromotionRedemption
+		if discountAmount > 0 {
+			redemption = global.PromotionRedemption{
+				UserID:         user.ID,
+				PharmacyID:     pharmacy.ID,
+				MaskID:         mask.ID,
+				Quantity:       in.Quantity,
+				DiscountAmount: discountAmount,
+				RedeemedAt:     now,
+			}
+			if err := redeemPromotion(tx, promotion, &redemption); err != nil {
+				var perr *promotionError
+				if errors.As(err, &perr) {
+					return &purchaseError{Status: http.StatusConflict, Code: perr.Code, Message: perr.Message}
+				}
+				return err
+			}
+		}
+
+		// Create purchase records (one for each quantity)
+		purchaseIDs := make([]uint, 0, in.Quantity)
+		unitCommissions := splitAmount(fee.Amount, in.Quantity)
+		unitDiscounts := splitAmount(discountAmount, in.Quantity)
+		unitPointsDiscounts := splitAmount(pointsDiscount, in.Quantity)
+		unitPointsRedeemed := splitPoints(pointsRedeemed, in.Quantity)
+		unitPointsEarned := splitPoints(pointsEarned, in.Quantity)
+		for i := 0; i < in.Quantity; i++ {
+			purchase := global.Purchase{
+				UserID:            user.ID,
+				PharmacyID:        pharmacy.ID,
+				MaskID:            mask.ID,
+				PharmacyName:      pharmacy.Name,
+				MaskName:          mask.Name,
+				TransactionAmount: roundCurrency(unitPrice - unitDiscounts[i] - unitPointsDiscounts[i]),
+				CommissionAmount:  unitCommissions[i],
+				DiscountAmount:    unitDiscounts[i],
+				PointsRedeemed:    unitPointsRedeemed[i],
+				PointsEarned:      unitPointsEarned[i],
+				TransactionDate:   now,
+			}
+			if discountAmount > 0 {
+				purchase.PromotionCode = promotion.Code
+				purchase.PromotionRedemptionID = &redemption.ID
+			}
+			if earnEntry != nil {
+				purchase.LoyaltyEntryID = &earnEntry.ID
+			}
+
+			if err := tx.Create(&purchase).Error; err != nil {
+				return err
+			}
+			purchaseIDs = append(purchaseIDs, purchase.ID)
+		}
+
+		response = &api.PurchaseResponse{
+			Success:     true,
+			Message:     "Purchase completed successfully",
+			PurchaseIDs: purchaseIDs,
+			Details: api.PurchaseDetails{
+				UserID:             user.ID,
+				UserName:           user.Name,
+				PharmacyID:         pharmacy.ID,
+				PharmacyName:       pharmacy.Name,
+				MaskID:             mask.ID,
+				MaskName:           mask.Name,
+				BaseUnitPrice:      mask.Price,
+				UnitPrice:          unitPrice,
+				PriceTier:          tier,
+				Quantity:           in.Quantity,
+				SubtotalAmount:     subtotalAmount,
+				CouponCode:         promotion.Code,
+				DiscountAmount:     discountAmount,
+				PointsRedeemed:     pointsRedeemed,
+				PointsDiscount:     pointsDiscount,
+				TotalAmount:        totalAmount,
+				CommissionPercent:  fee.Percent,
+				CommissionFixedFee: fee.FixedFee,
+				CommissionAmount:   fee.Amount,
+				PharmacyAmount:     pharmacyAmount,
+				PreviousBalance:    previousBalance,
+				NewBalance:         user.CashBalance,
+				PointsEarned:       pointsEarned,
+				PointsBalance:      user.LoyaltyPoints,
+			},
+			Timestamp: now,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// refundTotals is what a refund gives back to the user and who returns it
+type refundTotals struct {
+	Amount          float64
+	Commission      float64
+	PharmacyAmounts map[uint]float64 // by pharmacy
+	EarnedPoints    map[uint]int     // by the earn entry that credited them
+	RedeemedPoints  int
+	Redemptions     []uint // coupon uses that discounted the purchases
+}
+
+// sumRefund totals the refund of purchases
+func sumRefund(purchases []global.Purchase) refundTotals {
+	refund := refundTotals{
+		PharmacyAmounts: make(map[uint]float64),
+		EarnedPoints:    make(map[uint]int),
+	}
+	seen := make(map[uint]bool)
+	for _, purchase := range purchases {
+		refund.Amount += purchase.TransactionAmount
+		refund.Commission += purchase.CommissionAmount
+		refund.PharmacyAmounts[purchase.PharmacyID] += purchase.TransactionAmount - purchase.CommissionAmount
+		refund.RedeemedPoints += purchase.PointsRedeemed
+		if purchase.LoyaltyEntryID != nil {
+			refund.EarnedPoints[*purchase.LoyaltyEntryID] += purchase.PointsEarned
+		}
+		if id := purchase.PromotionRedemptionID; id != nil && !seen[*id] {
+			seen[*id] = true
+			refund.Redemptions = append(refund.Redemptions, *id)
+		}
+	}
+	refund.Amount = roundCurrency(refund.Amount)
+	return refund
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"reflect"
+	"testing"
+)
+
+func TestSumRefund(t *testing.T) {
+	entry, redemption := uint(9), uint(4)
+	// Two units of a purchase that redeemed 150 points and a coupon, and one plain purchase
+	purchases := []global.Purchase{
+		{PharmacyID: 1, TransactionAmount: 4.25, CommissionAmount: 0.5, PointsRedeemed: 75, PointsEarned: 4, LoyaltyEntryID: &entry, PromotionRedemptionID: &redemption},
+		{PharmacyID: 1, TransactionAmount: 4.25, CommissionAmount: 0.5, PointsRedeemed: 75, PointsEarned: 4, LoyaltyEntryID: &entry, PromotionRedemptionID: &redemption},
+		{PharmacyID: 2, TransactionAmount: 10.1, CommissionAmount: 1},
+	}
+
+	refund := sumRefund(purchases)
+	if refund.Amount != 18.6 || refund.Commission != 2 {
+		t.Errorf("Expected amount 18.6 and commission 2, got %v and %v", refund.Amount, refund.Commission)
+	}
+	if !reflect.DeepEqual(refund.PharmacyAmounts, map[uint]float64{1: 7.5, 2: 9.1}) {
+		t.Errorf("Unexpected pharmacy amounts %v", refund.PharmacyAmounts)
+	}
+	if refund.RedeemedPoints != 150 {
+		t.Errorf("Expected 150 redeemed points to restore, got %d", refund.RedeemedPoints)
+	}
+	if !reflect.DeepEqual(refund.EarnedPoints, map[uint]int{9: 8}) {
+		t.Errorf("Expected 8 earned points of entry 9 to reverse, got %v", refund.EarnedPoints)
+	}
+	if !reflect.DeepEqual(refund.Redemptions, []uint{4}) {
+		t.Errorf("Expected redemption 4 to release, got %v", refund.Redemptions)
+	}
+}
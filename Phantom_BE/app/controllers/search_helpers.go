@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"PhantomBE/global"
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// searchedTypes are the result types of a search with their tables and
+// result columns, in the order of searchTypeRank
+var searchedTypes = []struct {
+	resultType string
+	table      string
+	columns    string
+}{
+	{"pharmacy", "pharmacies", "id, name"},
+	{"user", "users", "id, name"},
+	{"mask", "masks", "id, name, price, pharmacy_id"},
+}
+
+// searchPriceBucketExpr labels the price range of a mask for the price facet
+const searchPriceBucketExpr = "CASE " +
+	"WHEN price < 10 THEN '0-10' " +
+	"WHEN price < 20 THEN '10-20' " +
+	"WHEN price < 50 THEN '20-50' " +
+	"ELSE '50+' END"
+
+// searchMatches is the query of the rows of table whose name matches
+// req.Query and which pass the filters of their result type
+func searchMatches(db *gorm.DB, resultType, table string, req api.SearchRequest) *gorm.DB {
+	// safe_search keeps LIKE wildcards out of the query, which is only ever bound
+	db = db.Table(table).Where(searchMatchExpr, sql.Named("q", req.Query))
+
+	switch {
+	case resultType == "mask" && req.Mask != nil:
+		if conditions, args := maskAttributeConditions(req.Mask.MaskAttributeFilter); conditions != "" {
+			db = db.Where(conditions, args...)
+		}
+		if req.Mask.MinPrice > 0 {
+			db = db.Where("masks.price >= ?", req.Mask.MinPrice)
+		}
+		if req.Mask.MaxPrice > 0 {
+			db = db.Where("masks.price <= ?", req.Mask.MaxPrice)
+		}
+		if req.Mask.PharmacyID != 0 {
+			db = db.Where("masks.pharmacy_id = ?", req.Mask.PharmacyID)
+		}
+	case resultType == "pharmacy" && req.Pharmacy != nil:
+		if req.Pharmacy.MinCashBalance != nil {
+			db = db.Where("pharmacies.cash_balance >= ?", *req.Pharmacy.MinCashBalance)
+		}
+		if req.Pharmacy.MaxCashBalance != nil {
+			db = db.Where("pharmacies.cash_balance <= ?", *req.Pharmacy.MaxCashBalance)
+		}
+	}
+	return db
+}
+
+// searchFacets counts all results of req by type, and the mask results by
+// brand, color, price bucket and pharmacy (the 20 with the most)
+func (pc *PharmacyController) searchFacets(ctx context.Context, req api.SearchRequest) (*api.SearchFacets, error) {
+	db := pc.db.WithContext(ctx)
+	facets := &api.SearchFacets{Types: make(map[string]int64)}
+
+	for _, search := range searchedTypes {
+		if req.Type != search.resultType && req.Type != "all" {
+			continue
+		}
+		var count int64
+		if err := searchMatches(db, search.resultType, search.table, req).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		facets.Types[search.resultType] = count
+		if search.resultType != "mask" || count == 0 {
+			continue
+		}
+
+		// Masks without a parsed brand or color are left out of those facets
+		for _, facet := range []struct {
+			expr   string
+			where  string
+			counts *[]api.FacetCount
+		}{
+			{"brand", "brand <> ''", &facets.Brands},
+			{"color", "color <> ''", &facets.Colors},
+			{searchPriceBucketExpr, "TRUE", &facets.PriceBuckets},
+		} {
+			if err := searchMatches(db, "mask", "masks", req).
+				Select(facet.expr + " AS value, COUNT(*) AS count").
+				Where(facet.where).
+				Group("value").
+				Order("count DESC, value").
+				Scan(facet.counts).Error; err != nil {
+				return nil, err
+			}
+		}
+		// Buckets read best in price order
+		sort.Slice(facets.PriceBuckets, func(i, j int) bool {
+			return priceBucketRank(facets.PriceBuckets[i].Value) < priceBucketRank(facets.PriceBuckets[j].Value)
+		})
+
+		if err := searchMatches(db, "mask", "masks", req).
+			Select("pharmacy_id, COUNT(*) AS count").
+			Group("pharmacy_id").
+			Order("count DESC, pharmacy_id").
+			Limit(20).
+			Scan(&facets.Pharmacies).Error; err != nil {
+			return nil, err
+		}
+		if err := pc.namePharmacyFacets(db, facets.Pharmacies); err != nil {
+			return nil, err
+		}
+	}
+	return facets, nil
+}
+
+// namePharmacyFacets fills in the pharmacy names of facets
+func (pc *PharmacyController) namePharmacyFacets(db *gorm.DB, facets []api.PharmacyFacet) error {
+	if len(facets) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(facets))
+	for i, facet := range facets {
+		ids[i] = facet.PharmacyID
+	}
+	var pharmacies []global.Pharmacy
+	if err := db.Select("id, name").Where("id IN ?", ids).Find(&pharmacies).Error; err != nil {
+		return err
+	}
+	names := make(map[uint]string, len(pharmacies))
+	for _, pharmacy := range pharmacies {
+		names[pharmacy.ID] = pharmacy.Name
+	}
+	for i := range facets {
+		facets[i].PharmacyName = names[facets[i].PharmacyID]
+	}
+	return nil
+}
+
+// priceBucketRank orders the labels of searchPriceBucketExpr by price
+func priceBucketRank(label string) int {
+	for i, bucket := range []string{"0-10", "10-20", "20-50", "50+"} {
+		if bucket == label {
+			return i
+		}
+	}
+	return 4
+}
+
+// highlightMatches returns the parts of name that match query, case-insensitively:
+// the whole query if name contains it, otherwise the first occurrence of each
+// query word. Offsets count characters, not bytes. Typo matches have none.
+func highlightMatches(name, query string) []api.Highlight {
+	lowerName := []rune(strings.ToLower(name))
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	if start := runeIndex(lowerName, []rune(query)); start >= 0 {
+		return []api.Highlight{{Start: start, End: start + utf8.RuneCountInString(query)}}
+	}
+
+	var highlights []api.Highlight
+	for _, word := range strings.Fields(query) {
+		if start := runeIndex(lowerName, []rune(word)); start >= 0 {
+			highlights = append(highlights, api.Highlight{Start: start, End: start + utf8.RuneCountInString(word)})
+		}
+	}
+	sort.Slice(highlights, func(i, j int) bool { return highlights[i].Start < highlights[j].Start })
+
+	// Words may overlap, e.g. "care" and "carepoint"
+	merged := highlights[:0]
+	for _, highlight := range highlights {
+		if n := len(merged); n > 0 && highlight.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, highlight.End)
+			continue
+		}
+		merged = append(merged, highlight)
+	}
+	return merged
+}
+
+// runeIndex is the character offset of the first sub in s, or -1
+func runeIndex(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if string(s[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
+}
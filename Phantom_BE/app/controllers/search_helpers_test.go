@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"PhantomBE/app/api"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestHighlightMatches(t *testing.T) {
+	tests := []struct {
+		name, query string
+		expected    []api.Highlight
+	}{
+		{"True Barrier (green) (3 per pack)", "barrier", []api.Highlight{{Start: 5, End: 12}}},
+		{"True Barrier (green) (3 per pack)", "green barrier", []api.Highlight{{Start: 5, End: 12}, {Start: 14, End: 19}}},
+		{"Carepoint", "care carepoint", []api.Highlight{{Start: 0, End: 9}}},
+		{"康是美藥局", "藥局", []api.Highlight{{Start: 3, End: 5}}},
+		{"True Barrier", "barier", nil},
+	}
+	for _, test := range tests {
+		result := highlightMatches(test.name, test.query)
+		if len(result) == 0 && len(test.expected) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("highlightMatches(%q, %q) = %v, expected %v", test.name, test.query, result, test.expected)
+		}
+	}
+}
+
+// dryRunDB builds statements without a database to run them on
+func dryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return db
+}
+
+func TestSearchQuery(t *testing.T) {
+	db := dryRunDB(t)
+	req := api.SearchRequest{Query: "Carepoint"}
+
+	statement := searchQuery(db, "pharmacy", "pharmacies", "id, name", req, nil).Find(&[]map[string]interface{}{}).Statement
+	sql := regexp.MustCompile(`\$\d+`).ReplaceAllString(statement.SQL.String(), "?")
+	expected := "SELECT * FROM (SELECT id, name, " + strings.ReplaceAll(searchRelevanceExpr, "@q", "?") + " AS relevance " +
+		`FROM "pharmacies" WHERE ` + strings.ReplaceAll(searchMatchExpr, "@q", "?") + ") AS matches ORDER BY relevance DESC, id"
+	if sql != expected {
+		t.Errorf("Expected %q, got %q", expected, sql)
+	}
+	for i, arg := range statement.Vars {
+		if arg != "Carepoint" {
+			t.Errorf("Expected the query bound as argument %d, got %v", i+1, arg)
+		}
+	}
+	if len(statement.Vars) != 10 {
+		t.Errorf("Expected the query bound 10 times, got %d", len(statement.Vars))
+	}
+}
+
+func TestSearchQueryFilters(t *testing.T) {
+	req := api.SearchRequest{
+		Query: "mask",
+		Mask:  &api.MaskSearchFilter{MinPrice: 5, PharmacyID: 3},
+	}
+	tests := []struct {
+		resultType, table string
+		expected          string
+	}{
+		{"mask", "masks", "AND masks.price >= $11 AND masks.pharmacy_id = $12) AS matches"},
+		{"user", "users", "LOWER($10)))) AS matches"},
+	}
+	for _, test := range tests {
+		sql := searchQuery(dryRunDB(t), test.resultType, test.table, "id, name", req, nil).Find(&[]map[string]interface{}{}).Statement.SQL.String()
+		if !strings.Contains(sql, test.expected) {
+			t.Errorf("%s: expected %q in %q", test.resultType, test.expected, sql)
+		}
+	}
+}
+
+func TestSearchQueryCursor(t *testing.T) {
+	req := api.SearchRequest{Query: "mask"}
+	after := &searchCursor{Relevance: 80, TypeRank: searchTypeRank["user"], ID: 7}
+	tests := []struct {
+		resultType, table string
+		expected          string
+		args              []interface{}
+	}{
+		{"pharmacy", "pharmacies", "AS matches WHERE relevance < $11 ORDER BY relevance DESC, id", []interface{}{80.0}},
+		{"user", "users", "AS matches WHERE (relevance < $11 OR (relevance = $12 AND id > $13)) ORDER BY relevance DESC, id", []interface{}{80.0, 80.0, uint(7)}},
+		{"mask", "masks", "AS matches WHERE relevance <= $11 ORDER BY relevance DESC, id", []interface{}{80.0}},
+	}
+	for _, test := range tests {
+		statement := searchQuery(dryRunDB(t), test.resultType, test.table, "id, name", req, after).Find(&[]map[string]interface{}{}).Statement
+		if sql := statement.SQL.String(); !strings.HasSuffix(sql, test.expected) || !reflect.DeepEqual(statement.Vars[10:], test.args) {
+			t.Errorf("%s: expected %q %v, got %q %v", test.resultType, test.expected, test.args, sql, statement.Vars[10:])
+		}
+	}
+}
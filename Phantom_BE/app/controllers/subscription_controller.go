@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"PhantomBE/app/api"
+	"errors"
+	"net/http"
+	"time"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SubscriptionController struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionController(db *gorm.DB) *SubscriptionController {
+	return &SubscriptionController{db: db}
+}
+
+// 1. Create a subscription that reorders a mask on a fixed schedule
+// POST /api/v1/subscriptions
+func (sc *SubscriptionController) CreateSubscription(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.CreateSubscriptionRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	db := sc.db.WithContext(ctx)
+
+	var user global.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "User not found",
+				Code:  "USER_NOT_FOUND",
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+
+	var mask global.Mask
+	if err := db.First(&mask, req.MaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Mask not found",
+				Code:  "MASK_NOT_FOUND",
+			})
+			return
+		}
+		abortWithDBError(c, err)
+		return
+	}
+	if mask.PharmacyID != req.PharmacyID {
+		c.JSON(http.StatusBadRequest, global.ErrorResponse{
+			Error: "Mask does not belong to specified pharmacy",
+			Code:  "MASK_PHARMACY_MISMATCH",
+			Details: gin.H{
+				"mask_pharmacy_id": mask.PharmacyID,
+				"requested_pharmacy_id": req.PharmacyID,
+			},
+		})
+		return
+	}
+
+	// Start time is read in the business time zone (validation ensures format)
+	nextRunAt := time.Now()
+	if req.StartAt != "" {
+		nextRunAt, _ = time.ParseInLocation("2006-01-02 15:04", req.StartAt, global.BusinessLocation())
+	}
+
+	subscription := global.Subscription{
+		UserID:     req.UserID,
+		PharmacyID: req.PharmacyID,
+		MaskID:     req.MaskID,
+		Quantity:   req.Quantity,
+		Frequency:  req.Frequency,
+		Every:      max(req.Every, 1),
+		NextRunAt:  nextRunAt,
+		StartsAt:   &nextRunAt,
+		Status:     "active",
+	}
+	if err := db.Create(&subscription).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, api.SubscriptionResponse{Subscription: subscription})
+}
+
+// 2. List subscriptions, optionally of one user or in one status
+// GET /api/v1/subscriptions?user_id=&status=&limit=
+func (sc *SubscriptionController) ListSubscriptions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.ListSubscriptionsRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	// Set default limit if not provided or zero
+	if req.Limit <= 0 {
+		req.Limit = 100
+	}
+
+	query := sc.db.WithContext(ctx).Model(&global.Subscription{})
+	if req.UserID > 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var subscriptions []global.Subscription
+	if err := query.Order("id").Limit(req.Limit).Find(&subscriptions).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.SubscriptionListResponse{
+		Subscriptions: subscriptions,
+		Count:         len(subscriptions),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 3. Get a single subscription
+// GET /api/v1/subscriptions/:id
+func (sc *SubscriptionController) GetSubscription(c *gin.Context) {
+	subscription, ok := sc.findSubscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, api.SubscriptionResponse{Subscription: subscription})
+}
+
+// 4. Change the quantity or schedule of a subscription, or pause / resume it
+// PUT /api/v1/subscriptions/:id
+func (sc *SubscriptionController) UpdateSubscription(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.UpdateSubscriptionRequest
+	if !bindRequest(c, &req) {
+		return
+	}
+
+	subscription, ok := sc.findSubscription(c)
+	if !ok {
+		return
+	}
+	if subscription.Status == "cancelled" {
+		c.JSON(http.StatusConflict, global.ErrorResponse{
+			Error: "Subscription is cancelled",
+			Code:  "SUBSCRIPTION_CANCELLED",
+		})
+		return
+	}
+
+	// Only the requested columns are written, so a run the scheduler claims
+	// meanwhile keeps its next_run_at and last run
+	updates := make(map[string]interface{})
+	if req.Quantity != nil {
+		updates["quantity"] = *req.Quantity
+	}
+	if req.Frequency != nil {
+		updates["frequency"] = *req.Frequency
+	}
+	if req.Every != nil {
+		updates["every"] = *req.Every
+	}
+	if req.NextRunAt != nil {
+		updates["next_run_at"], _ = time.ParseInLocation("2006-01-02 15:04", *req.NextRunAt, global.BusinessLocation())
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	// A new schedule counts its periods from its next run
+	if req.NextRunAt != nil {
+		updates["starts_at"] = updates["next_run_at"]
+	} else if req.Frequency != nil || req.Every != nil {
+		updates["starts_at"] = gorm.Expr("next_run_at")
+	}
+
+	if len(updates) > 0 {
+		db := sc.db.WithContext(ctx)
+		result := db.Model(&global.Subscription{}).
+			Where("id = ? AND status <> ?", subscription.ID, "cancelled").
+			Updates(updates)
+		if result.Error != nil {
+			abortWithDBError(c, result.Error)
+			return
+		}
+		if result.RowsAffected == 0 {
+			// Cancelled since it was read
+			c.JSON(http.StatusConflict, global.ErrorResponse{
+				Error: "Subscription is cancelled",
+				Code:  "SUBSCRIPTION_CANCELLED",
+			})
+			return
+		}
+		if err := db.First(&subscription, subscription.ID).Error; err != nil {
+			abortWithDBError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, api.SubscriptionResponse{Subscription: subscription})
+}
+
+// 5. Cancel a subscription, its run history is kept
+// DELETE /api/v1/subscriptions/:id
+func (sc *SubscriptionController) CancelSubscription(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	subscription, ok := sc.findSubscription(c)
+	if !ok {
+		return
+	}
+
+	subscription.Status = "cancelled"
+	if err := sc.db.WithContext(ctx).Model(&subscription).Update("status", subscription.Status).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.SubscriptionResponse{Subscription: subscription})
+}
+
+// 6. Run history of a subscription, newest first
+// GET /api/v1/subscriptions/:id/runs?limit=
+func (sc *SubscriptionController) GetSubscriptionRuns(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req api.SubscriptionRunsRequest
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	// Set default limit if not provided or zero
+	if req.Limit <= 0 {
+		req.Limit = 50
+	}
+
+	subscription, ok := sc.findSubscription(c)
+	if !ok {
+		return
+	}
+
+	var runs []global.SubscriptionRun
+	if err := sc.db.WithContext(ctx).
+		Where("subscription_id = ?", subscription.ID).
+		Order("run_at DESC, id DESC").
+		Limit(req.Limit).
+		Find(&runs).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	response := api.SubscriptionRunsResponse{
+		SubscriptionID: subscription.ID,
+		Runs:           runs,
+		Count:          len(runs),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// findSubscription loads the subscription named by the ":id" path parameter,
+// writing the error response when it cannot.
+func (sc *SubscriptionController) findSubscription(c *gin.Context) (global.Subscription, bool) {
+	var subscription global.Subscription
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return subscription, false
+	}
+
+	if err := sc.db.WithContext(c.Request.Context()).First(&subscription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "Subscription not found",
+				Code:  "SUBSCRIPTION_NOT_FOUND",
+			})
+			return subscription, false
+		}
+		abortWithDBError(c, err)
+		return subscription, false
+	}
+	return subscription, true
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"context"
+	"errors"
+	"time"
+	"github.com/charmbracelet/log"
+	"gorm.io/gorm"
+)
+
+// SubscriptionScheduler periodically executes due subscriptions through the
+// same purchase logic as the purchase API.
+type SubscriptionScheduler struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+func NewSubscriptionScheduler(db *gorm.DB, interval time.Duration) *SubscriptionScheduler {
+	return &SubscriptionScheduler{db: db, interval: interval}
+}
+
+// Start runs the scheduler in the background until ctx is cancelled
+func (s *SubscriptionScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if count, err := s.RunDue(ctx, time.Now()); err != nil {
+					log.Error("subscription run failed", "err", err)
+				} else if count > 0 {
+					log.Info("subscriptions executed", "count", count)
+				}
+			}
+		}
+	}()
+}
+
+// RunDue executes every active subscription whose next run is at or before now
+// and returns how many were executed. A subscription that fails is logged and
+// does not keep the others from running.
+func (s *SubscriptionScheduler) RunDue(ctx context.Context, now time.Time) (int, error) {
+	var due []global.Subscription
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND next_run_at <= ?", "active", now).
+		Order("next_run_at").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	executed := 0
+	for _, subscription := range due {
+		ran, err := s.runSubscription(ctx, subscription, now)
+		if err != nil {
+			log.Error("subscription run failed", "subscription", subscription.ID, "err", err)
+		}
+		if ran {
+			executed++
+		}
+	}
+	return executed, nil
+}
+
+// runSubscription claims the subscription by moving its next run forward, then
+// attempts the purchase and records the outcome. It returns false when another
+// scheduler instance claimed the subscription first.
+func (s *SubscriptionScheduler) runSubscription(ctx context.Context, subscription global.Subscription, now time.Time) (bool, error) {
+	db := s.db.WithContext(ctx)
+
+	claim := db.Model(&global.Subscription{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", subscription.ID, "active", subscription.NextRunAt).
+		Update("next_run_at", nextRunAfter(subscription, now))
+	if claim.Error != nil {
+		return false, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return false, nil
+	}
+
+	run := global.SubscriptionRun{
+		SubscriptionID: subscription.ID,
+		ScheduledAt:    subscription.NextRunAt,
+		RunAt:          now,
+		Status:         "failed",
+	}
+
+	response, err := executePurchase(ctx, s.db, purchaseInput{
+		UserID:     subscription.UserID,
+		PharmacyID: subscription.PharmacyID,
+		MaskID:     subscription.MaskID,
+		Quantity:   subscription.Quantity,
+	})
+	var perr *purchaseError
+	switch {
+	case errors.As(err, &perr) && perr.Code == "MASK_NOT_FOUND":
+		// Masks carry no stock count; a mask the pharmacy no longer lists is out of stock
+		run.ErrorCode, run.ErrorMessage = "OUT_OF_STOCK", "Mask is no longer available at this pharmacy"
+	case errors.As(err, &perr):
+		run.ErrorCode, run.ErrorMessage = perr.Code, perr.Message
+	case err != nil:
+		run.ErrorCode, run.ErrorMessage = "DB_ERROR", err.Error()
+	default:
+		run.Status = "success"
+		run.PurchaseIDs = response.PurchaseIDs
+		run.TotalAmount = response.Details.TotalAmount
+	}
+
+	if err := db.Create(&run).Error; err != nil {
+		return true, err
+	}
+	err = db.Model(&global.Subscription{}).
+		Where("id = ?", subscription.ID).
+		Updates(map[string]interface{}{
+			"last_run_at":     now,
+			"last_run_status": run.Status,
+		}).Error
+	return true, err
+}
+
+// nextRunAfter is the first run of the subscription schedule after now. Runs
+// are counted in whole periods from the start of the schedule, so a monthly
+// run clamped to a short month returns to the original day after it. Runs
+// missed while the scheduler was down are skipped rather than executed in a burst.
+func nextRunAfter(subscription global.Subscription, now time.Time) time.Time {
+	every := max(subscription.Every, 1)
+	start := subscription.NextRunAt
+	if subscription.StartsAt != nil {
+		start = *subscription.StartsAt
+	}
+	start = start.In(global.BusinessLocation())
+	for periods := every; ; periods += every {
+		var next time.Time
+		switch subscription.Frequency {
+		case "daily":
+			next = start.AddDate(0, 0, periods)
+		case "weekly":
+			next = start.AddDate(0, 0, 7*periods)
+		default:
+			next = addMonths(start, periods)
+		}
+		if next.After(now) {
+			return next
+		}
+	}
+}
+
+// addMonths moves t by months, clamped to the last day of the target month
+// so that e.g. Jan 31 is followed by Feb 28 rather than Mar 3
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
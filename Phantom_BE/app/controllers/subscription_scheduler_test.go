@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+	"PhantomBE/global"
+)
+
+func TestNextRunAfter(t *testing.T) {
+	loc := global.BusinessLocation()
+	start := time.Date(2025, 1, 31, 10, 0, 0, 0, loc)
+
+	cases := []struct {
+		name         string
+		subscription global.Subscription
+		now          time.Time
+		expected     time.Time
+	}{
+		{"Daily", global.Subscription{Frequency: "daily", Every: 1, NextRunAt: start}, start, time.Date(2025, 2, 1, 10, 0, 0, 0, loc)},
+		{"EveryTwoWeeks", global.Subscription{Frequency: "weekly", Every: 2, NextRunAt: start}, start, time.Date(2025, 2, 14, 10, 0, 0, 0, loc)},
+		{"Monthly", global.Subscription{Frequency: "monthly", Every: 1, NextRunAt: start}, start, time.Date(2025, 2, 28, 10, 0, 0, 0, loc)},
+		{"MonthlyLeapYear", global.Subscription{Frequency: "monthly", Every: 1, NextRunAt: start.AddDate(-1, 0, 0)}, start.AddDate(-1, 0, 0), time.Date(2024, 2, 29, 10, 0, 0, 0, loc)},
+		{"EveryTwoMonths", global.Subscription{Frequency: "monthly", Every: 2, NextRunAt: start}, start, time.Date(2025, 3, 31, 10, 0, 0, 0, loc)},
+		{"SkipsMissedRuns", global.Subscription{Frequency: "daily", Every: 1, NextRunAt: start}, start.AddDate(0, 0, 5).Add(time.Hour), time.Date(2025, 2, 6, 10, 0, 0, 0, loc)},
+	}
+	for _, tc := range cases {
+		if result := nextRunAfter(tc.subscription, tc.now); !result.Equal(tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, result)
+		}
+	}
+
+	// Consecutive monthly runs, each claimed when due, return to the month end
+	// after a short month
+	subscription := global.Subscription{Frequency: "monthly", Every: 1, NextRunAt: start, StartsAt: &start}
+	for _, expected := range []time.Time{
+		time.Date(2025, 2, 28, 10, 0, 0, 0, loc),
+		time.Date(2025, 3, 31, 10, 0, 0, 0, loc),
+		time.Date(2025, 4, 30, 10, 0, 0, 0, loc),
+		time.Date(2025, 5, 31, 10, 0, 0, 0, loc),
+	} {
+		next := nextRunAfter(subscription, subscription.NextRunAt)
+		if !next.Equal(expected) {
+			t.Fatalf("After %v: expected %v, got %v", subscription.NextRunAt, expected, next)
+		}
+		subscription.NextRunAt = next
+	}
+
+	// Missed runs are skipped to the month end as well
+	subscription.NextRunAt = start
+	if next := nextRunAfter(subscription, time.Date(2025, 3, 1, 0, 0, 0, 0, loc)); !next.Equal(time.Date(2025, 3, 31, 10, 0, 0, 0, loc)) {
+		t.Errorf("Expected missed runs skipped to Mar 31, got %v", next)
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"PhantomBE/global"
+	"PhantomBE/app/api"
+	"PhantomBE/app/pagination"
+	"errors"
+	"net/http"
+	"time"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserController struct {
+	db *gorm.DB
+}
+
+func NewUserController(db *gorm.DB) *UserController {
+	return &UserController{db: db}
+}
+
+// purchaseCursor is the position after the last purchase of a page, newest first
+type purchaseCursor struct {
+	TransactionDate time.Time `json:"transaction_date"`
+	ID              uint      `json:"id"`
+}
+
+// 1. Get a user with their balances
+// GET /api/v2/users/:id
+func (uc *UserController) GetUser(c *gin.Context) {
+	user, ok := uc.findUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, api.UserResponse{User: user})
+}
+
+// 2. Purchase history of a user, newest first
+// GET /api/v2/users/:id/purchases?start_date=&end_date=&pharmacy_id=
+func (uc *UserController) ListUserPurchases(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	req := api.UserPurchasesRequest{UserID: id}
+	if !bindQuery(c, &req) {
+		return
+	}
+
+	pageSize := pagination.PageSize(req.PageSize)
+	var after purchaseCursor
+	if !decodeCursor(c, req.Cursor, &after) {
+		return
+	}
+
+	user, ok := uc.findUser(c)
+	if !ok {
+		return
+	}
+
+	query := uc.db.WithContext(ctx).Where("user_id = ?", user.ID)
+	// Parse dates (validation already ensures correct format), the end date includes the entire day
+	if req.StartDate != "" {
+		startDate, _ := time.Parse("2006-01-02", req.StartDate)
+		query = query.Where("transaction_date >= ?", startDate)
+	}
+	if req.EndDate != "" {
+		endDate, _ := time.Parse("2006-01-02", req.EndDate)
+		query = query.Where("transaction_date < ?", endDate.Add(24*time.Hour))
+	}
+	if req.PharmacyID > 0 {
+		query = query.Where("pharmacy_id = ?", req.PharmacyID)
+	}
+	if req.Cursor != "" {
+		query = query.Where("(transaction_date, id) < (?, ?)", after.TransactionDate, after.ID)
+	}
+
+	var purchases []global.Purchase
+	if err := query.
+		Order("transaction_date DESC, id DESC").
+		Limit(pageSize + 1).
+		Find(&purchases).Error; err != nil {
+		abortWithDBError(c, err)
+		return
+	}
+
+	purchases, hasMore := pagination.Trim(purchases, pageSize)
+	var last purchaseCursor
+	if len(purchases) > 0 {
+		lastPurchase := purchases[len(purchases)-1]
+		last = purchaseCursor{TransactionDate: lastPurchase.TransactionDate, ID: lastPurchase.ID}
+	}
+
+	response := api.UserPurchasesResponse{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		Purchases: purchases,
+		Count:     len(purchases),
+		PageInfo:  newPageInfo(pageSize, hasMore, last),
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// findUser loads the user named by the ":id" path parameter, writing the
+// error response when it cannot.
+func (uc *UserController) findUser(c *gin.Context) (global.User, bool) {
+	var user global.User
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return user, false
+	}
+
+	if err := uc.db.WithContext(c.Request.Context()).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, global.ErrorResponse{
+				Error: "User not found",
+				Code:  "USER_NOT_FOUND",
+				Details: gin.H{
+					"user_id": id,
+				},
+			})
+			return user, false
+		}
+		abortWithDBError(c, err)
+		return user, false
+	}
+	return user, true
+}
@@ -0,0 +1,52 @@
+package geo
+
+import "math"
+
+// geo.go computes great-circle distances between pharmacy coordinates
+
+// EarthRadiusKm is the mean radius of the Earth
+const EarthRadiusKm = 6371.0
+
+// kmPerDegreeLatitude is the length of one degree of latitude
+const kmPerDegreeLatitude = 111.32
+
+// DistanceKm is the haversine distance between two points in degrees
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLng := radians(lng2 - lng1)
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// Box is a latitude/longitude range containing a circle. Longitude is
+// unbounded (MinLng -180, MaxLng 180) when the circle reaches a pole or
+// crosses the antimeridian.
+type Box struct {
+	MinLat, MaxLat float64
+	MinLng, MaxLng float64
+}
+
+// BoundingBox returns the box containing the circle of radiusKm around a point
+func BoundingBox(lat, lng, radiusKm float64) Box {
+	dLat := radiusKm / kmPerDegreeLatitude
+	box := Box{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+	// Meridians converge, so a degree of longitude shrinks with the cosine of the latitude
+	dLng := radiusKm / (kmPerDegreeLatitude * math.Cos(radians(math.Max(math.Abs(box.MinLat), math.Abs(box.MaxLat)))))
+	if lng-dLng >= -180 && lng+dLng <= 180 {
+		box.MinLng, box.MaxLng = lng-dLng, lng+dLng
+	}
+	return box
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
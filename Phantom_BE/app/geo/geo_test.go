@@ -0,0 +1,45 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		expected               float64
+	}{
+		{"same point", 25.0330, 121.5654, 25.0330, 121.5654, 0},
+		{"Taipei 101 to Taipei Main Station", 25.0340, 121.5645, 25.0478, 121.5170, 5.0},
+		{"Taipei to Kaohsiung", 25.0330, 121.5654, 22.6273, 120.3014, 297.0},
+		{"across the antimeridian", 0, 179.9, 0, -179.9, 22.2},
+	}
+	for _, test := range tests {
+		distance := DistanceKm(test.lat1, test.lng1, test.lat2, test.lng2)
+		if math.Abs(distance-test.expected) > 0.5 {
+			t.Errorf("%s: expected about %v km, got %v", test.name, test.expected, distance)
+		}
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	box := BoundingBox(25.0330, 121.5654, 5)
+	for _, point := range [][2]float64{{25.0330, 121.5654}, {25.0770, 121.5654}, {25.0330, 121.6134}} {
+		if point[0] < box.MinLat || point[0] > box.MaxLat || point[1] < box.MinLng || point[1] > box.MaxLng {
+			t.Errorf("Expected %v within %+v", point, box)
+		}
+	}
+	if box.MaxLat-box.MinLat > 0.1 || box.MaxLng-box.MinLng > 0.12 {
+		t.Errorf("Expected a tight box around 5 km, got %+v", box)
+	}
+
+	// Near the antimeridian or a pole longitude is unbounded
+	if box := BoundingBox(0, 179.99, 10); box.MinLng != -180 || box.MaxLng != 180 {
+		t.Errorf("Expected unbounded longitude across the antimeridian, got %+v", box)
+	}
+	if box := BoundingBox(89.99, 0, 10); box.MaxLat != 90 || box.MinLng != -180 {
+		t.Errorf("Expected the box to reach the pole, got %+v", box)
+	}
+}
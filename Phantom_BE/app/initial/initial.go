@@ -4,6 +4,8 @@ import(
 
 	"PhantomBE/global"
 	"PhantomBE/app/models"
+	"PhantomBE/app/catalog"
+	"PhantomBE/app/schedule"
 	"github.com/charmbracelet/log"
 	"os"
 	"fmt"
@@ -90,13 +92,35 @@ func InitSamplePharmacies() {
 
 	for _, rp := range rawPharmacies {
 		// Normalize openingHours
-		parsed := parseOpeningHours(rp.OpeningHoursRaw)
+		log.Info("Analyzed days", "raw", rp.OpeningHoursRaw)
+		parsed, changes := schedule.Normalize(schedule.ParseOpeningHours(rp.OpeningHoursRaw))
+		for _, change := range changes {
+			log.Warn("normalized opening hours", "pharmacy", rp.Name, "action", change.Action, "change", change.Message)
+		}
+
+		// Masks without a product would break the offers of the products table
+		masks := rp.Masks[:0]
+		for _, mask := range rp.Masks {
+			if err := catalog.Link(models.DBPharmacy, &mask); err != nil {
+				log.Error("skipping mask without product", "pharmacy", rp.Name, "mask", mask.Name, "error", err)
+				continue
+			}
+			masks = append(masks, mask)
+		}
+		rp.Masks = masks
 
 		pharmacy := global.Pharmacy{
 			Name: rp.Name,
 			CashBalance: rp.CashBalance,
 			OpeningHours: parsed,
 			Masks: rp.Masks,
+			Address: rp.Address,
+		}
+		// Coordinates are only kept as a pair
+		if rp.Latitude != nil && rp.Longitude != nil {
+			pharmacy.Latitude, pharmacy.Longitude = rp.Latitude, rp.Longitude
+		} else if rp.Latitude != nil || rp.Longitude != nil {
+			log.Warn("ignoring incomplete coordinates", "pharmacy", rp.Name)
 		}
 
 		// processedPharmacies = append(processedPharmacies, pharmacy)
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"PhantomBE/global"
+	"crypto/subtle"
 	"net/http"
+	"strings"
 	"time"
 	"github.com/gin-gonic/gin"
 )
@@ -43,6 +46,22 @@ func IsLoggedIn() gin.HandlerFunc {
 }
 
 // IsSysAdm checks if user is system admin.
+// Admins send ADMIN_API_TOKEN as "Authorization: Bearer <token>" or in the "Token"
+// header; without a configured token every admin request is rejected.
 func IsSysAdm() gin.HandlerFunc {
-	return func(c *gin.Context) {}
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.GetHeader("Token")
+		}
+		if global.AdminAPIToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(global.AdminAPIToken)) != 1 {
+			c.JSON(http.StatusUnauthorized, global.ErrorResponse{
+				Error: "Admin authorization required",
+				Code:  "UNAUTHORIZED",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
 }
@@ -7,6 +7,7 @@ import (
 	// "os"
 	// "strings"
 
+	"PhantomBE/app/catalog"
 	"PhantomBE/global"
 	"github.com/charmbracelet/log"
 
@@ -108,10 +109,99 @@ func CloseConnect(dbName string, DB *gorm.DB) {
 }
 func MigrateSchema() error {
 	// Retrieve the underlying SQL database connection.
-	if err := DBPharmacy.AutoMigrate(&global.User{}, &global.Purchase{}, &global.Pharmacy{}, &global.Mask{}, &global.OpeningHour{}); err != nil {
+	if err := DBPharmacy.AutoMigrate(&global.User{}, &global.Purchase{}, &global.Pharmacy{}, &global.Product{}, &global.Mask{}, &global.MaskPriceTier{}, &global.OpeningHour{}, &global.OpeningOverride{}, &global.Holiday{}, &global.PlatformAccount{}, &global.Promotion{}, &global.PromotionRedemption{}, &global.LoyaltyPointEntry{}, &global.Subscription{}, &global.SubscriptionRun{}); err != nil {
 		log.Error("failed to auto migrate DB", "err" , err)
 		return err
 	}
 	log.Info("Schema migrated successfully")
+	if err := createSearchIndexes(); err != nil {
+		return err
+	}
+	if err := backfillMaskAttributes(); err != nil {
+		return err
+	}
+	return linkMaskProducts()
+}
+
+// searchedTables are the tables searched by name
+var searchedTables = []string{"pharmacies", "users", "masks"}
+
+// createSearchIndexes enables pg_trgm and indexes the lower-cased names of the
+// searched tables for trigram similarity, substring and full-text matching
+func createSearchIndexes() error {
+	for _, statement := range searchIndexStatements() {
+		if err := DBPharmacy.Exec(statement).Error; err != nil {
+			log.Error("failed to create search index", "statement", statement, "err", err)
+			return err
+		}
+	}
 	return nil
 }
+
+// searchIndexStatements are the statements creating the search indexes, after
+// the pg_trgm extension they need
+func searchIndexStatements() []string {
+	statements := []string{"CREATE EXTENSION IF NOT EXISTS pg_trgm"}
+	for _, table := range searchedTables {
+		statements = append(statements,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_name_trgm ON %s USING gin (LOWER(name) gin_trgm_ops)", table, table),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_name_tsv ON %s USING gin (to_tsvector('simple', LOWER(name)))", table, table),
+		)
+	}
+	return statements
+}
+
+// backfillMaskAttributes parses brand, color and pack size of masks stored
+// before they had them. AutoMigrate adds those columns as NULL to existing rows.
+func backfillMaskAttributes() error {
+	var masks []global.Mask
+	return DBPharmacy.Where("pack_size IS NULL OR pack_size = ?", 0).FindInBatches(&masks, 500, func(tx *gorm.DB, batch int) error {
+		for i := range masks {
+			catalog.Describe(&masks[i])
+			if err := tx.Model(&masks[i]).Updates(map[string]interface{}{
+				"brand":          masks[i].Brand,
+				"color":          masks[i].Color,
+				"pack_size":      masks[i].PackSize,
+				"price_per_unit": masks[i].PricePerUnit,
+			}).Error; err != nil {
+				log.Error("failed to backfill mask attributes", "mask", masks[i].ID, "err", err)
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// linkMaskProducts creates one product per distinct name of the masks stored
+// before products existed and points those masks at it. Mask IDs are kept, so
+// purchases and subscriptions referencing them stay valid.
+func linkMaskProducts() error {
+	err := DBPharmacy.Transaction(func(tx *gorm.DB) error {
+		// The lowest mask ID of a name decides the product's attributes
+		if err := tx.Exec(`
+			INSERT INTO products (name, brand, color, pack_size)
+			SELECT DISTINCT ON (name) name, brand, color, pack_size
+			FROM masks
+			WHERE product_id IS NULL OR product_id = 0
+			ORDER BY name, id
+			ON CONFLICT (name) DO NOTHING`).Error; err != nil {
+			return err
+		}
+		result := tx.Exec(`
+			UPDATE masks SET product_id = products.id
+			FROM products
+			WHERE masks.name = products.name
+			AND (masks.product_id IS NULL OR masks.product_id = 0)`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Info("linked masks to products", "masks", result.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to link masks to products", "err", err)
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchIndexStatements(t *testing.T) {
+	statements := searchIndexStatements()
+	if len(statements) != 1+2*len(searchedTables) || statements[0] != "CREATE EXTENSION IF NOT EXISTS pg_trgm" {
+		t.Fatalf("Expected pg_trgm first and two indexes per table, got %v", statements)
+	}
+
+	// The indexed expressions must be the ones searches match on
+	for i, table := range searchedTables {
+		trigram, fullText := statements[1+2*i], statements[2+2*i]
+		if !strings.HasPrefix(trigram, "CREATE INDEX IF NOT EXISTS idx_"+table+"_name_trgm ON "+table) ||
+			!strings.HasSuffix(trigram, "USING gin (LOWER(name) gin_trgm_ops)") {
+			t.Errorf("Unexpected trigram index %q", trigram)
+		}
+		if !strings.HasPrefix(fullText, "CREATE INDEX IF NOT EXISTS idx_"+table+"_name_tsv ON "+table) ||
+			!strings.HasSuffix(fullText, "USING gin (to_tsvector('simple', LOWER(name)))") {
+			t.Errorf("Unexpected full-text index %q", fullText)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// pagination.go implements opaque keyset cursors for list endpoints.
+// A cursor carries the sort key of the last item of a page; the next page
+// starts strictly after it, so pages stay stable while rows are added.
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageSize applies the default and maximum to a requested page size
+func PageSize(requested int) int {
+	if requested <= 0 {
+		return DefaultPageSize
+	}
+	return min(requested, MaxPageSize)
+}
+
+// Encode packs the sort key of the last item on a page into a cursor
+func Encode(key interface{}) string {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode unpacks a cursor produced by Encode into key
+func Decode(cursor string, key interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, key); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// Trim cuts items fetched with a limit of pageSize+1 down to one page and
+// reports whether there are more items after it.
+func Trim[T any](items []T, pageSize int) ([]T, bool) {
+	if len(items) > pageSize {
+		return items[:pageSize], true
+	}
+	return items, false
+}
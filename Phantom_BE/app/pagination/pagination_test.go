@@ -0,0 +1,45 @@
+package pagination
+
+import (
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	type key struct {
+		Price float64 `json:"price"`
+		ID    uint    `json:"id"`
+	}
+	cursor := Encode(key{Price: 13.7, ID: 42})
+
+	var decoded key
+	if err := Decode(cursor, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.Price != 13.7 || decoded.ID != 42 {
+		t.Errorf("Expected {13.7 42}, got %+v", decoded)
+	}
+
+	if err := Decode("not a cursor!", &decoded); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	page, more := Trim([]int{1, 2, 3}, 2)
+	if len(page) != 2 || !more {
+		t.Errorf("Expected 2 items with more, got %v %v", page, more)
+	}
+	page, more = Trim([]int{1, 2}, 2)
+	if len(page) != 2 || more {
+		t.Errorf("Expected 2 items without more, got %v %v", page, more)
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	if size := PageSize(0); size != DefaultPageSize {
+		t.Errorf("Expected %d, got %d", DefaultPageSize, size)
+	}
+	if size := PageSize(5000); size != MaxPageSize {
+		t.Errorf("Expected %d, got %d", MaxPageSize, size)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"PhantomBE/app/controllers"
 	"PhantomBE/app/models"
+	"PhantomBE/app/middleware"
 	"PhantomBE/global"
 	"github.com/gin-gonic/gin"
 )
@@ -10,8 +11,9 @@ import (
 // router.go configures all API routes
 
 var (
-	Router      *gin.Engine
-	RouterGroup *gin.RouterGroup
+	Router        *gin.Engine
+	RouterGroup   *gin.RouterGroup
+	RouterGroupV2 *gin.RouterGroup
 )
 
 func ConfigureRoutes() {
@@ -26,6 +28,14 @@ func ConfigureRoutes() {
 	configureHelloRoute()
 	// configure test function routing
 	configurePharmacyRoutes()
+	configurePromotionRoutes()
+	configureLoyaltyRoutes()
+	configureSubscriptionRoutes()
+	configureAdminRoutes()
+
+	// RESTful GET resources, the v1 POST routes above keep working
+	RouterGroupV2 = Router.Group("/api/" + global.RESOURCE_VERSION)
+	configureResourceRoutes()
 }
 
 func configureHelloRoute(){
@@ -43,13 +53,106 @@ func configurePharmacyRoutes() {
 	{
 		// List pharmacies open at specific time/day
 		pharmacyGroup.POST("/open", pc.GetOpenPharmacies)
+		pharmacyGroup.POST("/opening-soon", pc.GetOpeningSoon)
+		pharmacyGroup.POST("/nearby", pc.GetNearbyPharmacies)
 		pharmacyGroup.POST("/masks", pc.GetPharmacyMasks)
 		pharmacyGroup.POST("/filter", pc.GetPharmaciesByMaskCount)
+		pharmacyGroup.POST("/filter/composite", pc.FilterPharmacies)
 		pharmacyGroup.POST("/users/top", pc.GetTopUsers)
 		pharmacyGroup.POST("/transactions/summary", pc.GetTransactionSummary)
 		pharmacyGroup.POST("/search", pc.Search)
+		pharmacyGroup.POST("/suggest", pc.Suggest)
 		pharmacyGroup.POST("/purchase", pc.ProcessPurchase)
+		pharmacyGroup.POST("/purchases/refund", middleware.IsSysAdm(), pc.RefundPurchases)
+		pharmacyGroup.POST("/commissions/summary", pc.GetCommissionSummary)
+		pharmacyGroup.POST("/masks/compare", pc.ComparePrices)
+		pharmacyGroup.POST("/masks/tiers", middleware.IsSysAdm(), pc.SetMaskPriceTiers)
+		pharmacyGroup.POST("/orders/availability", middleware.IsSysAdm(), pc.SetOrderAvailability)
 		pharmacyGroup.GET("/health", pc.HealthCheck)
 		
 	}
-}
\ No newline at end of file
+}
+func configurePromotionRoutes() {
+	prc := controllers.NewPromotionController(models.DBPharmacy)
+
+	promotionGroup := RouterGroup.Group("/promotions")
+	{
+		promotionGroup.POST("", middleware.IsSysAdm(), prc.CreatePromotion)
+		promotionGroup.GET("", middleware.IsSysAdm(), prc.ListPromotions)
+		promotionGroup.POST("/preview", prc.PreviewPromotion)
+	}
+}
+
+func configureLoyaltyRoutes() {
+	lc := controllers.NewLoyaltyController(models.DBPharmacy)
+
+	loyaltyGroup := RouterGroup.Group("/loyalty")
+	{
+		loyaltyGroup.POST("/balance", lc.GetPointsBalance)
+	}
+}
+
+func configureSubscriptionRoutes() {
+	sc := controllers.NewSubscriptionController(models.DBPharmacy)
+
+	subscriptionGroup := RouterGroup.Group("/subscriptions")
+	{
+		subscriptionGroup.POST("", sc.CreateSubscription)
+		subscriptionGroup.GET("", sc.ListSubscriptions)
+		subscriptionGroup.GET("/:id", sc.GetSubscription)
+		subscriptionGroup.PUT("/:id", sc.UpdateSubscription)
+		subscriptionGroup.DELETE("/:id", sc.CancelSubscription)
+		subscriptionGroup.GET("/:id/runs", sc.GetSubscriptionRuns)
+	}
+}
+
+func configureAdminRoutes() {
+	ac := controllers.NewAdminController(models.DBPharmacy)
+
+	adminGroup := RouterGroup.Group("/admin", middleware.IsSysAdm())
+	{
+		adminGroup.POST("/pharmacies", ac.CreatePharmacy)
+		adminGroup.PUT("/pharmacies/:id", ac.UpdatePharmacy)
+		adminGroup.DELETE("/pharmacies/:id", ac.DeletePharmacy)
+		adminGroup.PUT("/pharmacies/:id/opening-hours", ac.ReplaceOpeningHours)
+		adminGroup.GET("/pharmacies/:id/overrides", ac.ListOpeningOverrides)
+		adminGroup.PUT("/pharmacies/:id/overrides/:date", ac.SetOpeningOverride)
+		adminGroup.DELETE("/pharmacies/:id/overrides/:date", ac.DeleteOpeningOverride)
+		adminGroup.POST("/pharmacies/:id/masks", ac.CreateMask)
+		adminGroup.PUT("/masks/:id", ac.UpdateMask)
+		adminGroup.DELETE("/masks/:id", ac.DeleteMask)
+		adminGroup.GET("/holidays", ac.ListHolidays)
+		adminGroup.PUT("/holidays/:date", ac.SetHoliday)
+		adminGroup.DELETE("/holidays/:date", ac.DeleteHoliday)
+	}
+}
+
+func configureResourceRoutes() {
+	pc := controllers.NewPharmacyController(models.DBPharmacy)
+	uc := controllers.NewUserController(models.DBPharmacy)
+
+	pharmacyGroup := RouterGroupV2.Group("/pharmacies")
+	{
+		pharmacyGroup.GET("", pc.ListPharmacies)
+		pharmacyGroup.GET("/filter", pc.ListFilteredPharmacies)
+		pharmacyGroup.GET("/nearby", pc.ListNearbyPharmacies)
+		pharmacyGroup.GET("/opening-soon", pc.ListOpeningSoon)
+		pharmacyGroup.GET("/:id", pc.GetPharmacy)
+		pharmacyGroup.GET("/:id/masks", pc.ListPharmacyMasks)
+		pharmacyGroup.GET("/:id/schedule", pc.GetPharmacySchedule)
+	}
+
+	maskGroup := RouterGroupV2.Group("/masks")
+	{
+		maskGroup.GET("/compare", pc.ListPriceComparison)
+		maskGroup.GET("/:id", pc.GetMask)
+	}
+
+	RouterGroupV2.GET("/suggest", pc.ListSuggestions)
+
+	userGroup := RouterGroupV2.Group("/users")
+	{
+		userGroup.GET("/:id", uc.GetUser)
+		userGroup.GET("/:id/purchases", uc.ListUserPurchases)
+	}
+}
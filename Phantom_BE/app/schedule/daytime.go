@@ -0,0 +1,75 @@
+package schedule
+
+import (
+	"PhantomBE/global"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// daytime.go parses the days and times people write, both in the pharmacy data
+// and in API requests, into the normalized values stored in OpeningHour:
+// full English day names and 24-hour "HH:MM" clock times.
+
+// dayAliases maps lower-cased spellings of a day to its full name
+var dayAliases = func() map[string]string {
+	aliases := map[string]string{
+		"tues": "Tuesday", "weds": "Wednesday", "thur": "Thursday", "thurs": "Thursday",
+		"星期天": "Sunday", "禮拜天": "Sunday", "礼拜天": "Sunday",
+	}
+	numerals := []string{"一", "二", "三", "四", "五", "六", "日"}
+	for i, day := range global.Days {
+		lower := strings.ToLower(day)
+		aliases[lower] = day
+		aliases[lower[:3]] = day
+		for _, prefix := range []string{"星期", "週", "周", "禮拜", "礼拜"} {
+			aliases[prefix+numerals[i]] = day
+		}
+	}
+	return aliases
+}()
+
+// ParseDay returns the full English name of a day of the week given in full,
+// abbreviated ("Thur", "Tues") or in Chinese ("星期一", "週五"), ignoring case
+// and a trailing period.
+func ParseDay(day string) (string, bool) {
+	full, ok := dayAliases[strings.ToLower(strings.TrimSuffix(strings.TrimSpace(day), "."))]
+	return full, ok
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm|a\.m\.|p\.m\.)?$`)
+
+// ParseClock returns a time of day as 24-hour "HH:MM". It accepts "14:30",
+// "14.30", "9", 12-hour times such as "2pm" or "2:30 PM", and "24:00" for
+// the end of the day.
+func ParseClock(value string) (string, bool) {
+	match := clockPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if match == nil {
+		return "", false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	if minute > 59 {
+		return "", false
+	}
+
+	switch match[3] {
+	case "":
+		if hour > 24 || (hour == 24 && minute != 0) {
+			return "", false
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return "", false
+		}
+		hour %= 12
+		if strings.HasPrefix(match[3], "p") {
+			hour += 12
+		}
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), true
+}
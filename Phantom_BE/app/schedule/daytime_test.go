@@ -0,0 +1,38 @@
+package schedule
+
+import "testing"
+
+func TestParseDay(t *testing.T) {
+	cases := map[string]string{
+		"Monday": "Monday", "monday": "Monday", "Mon": "Monday", "Thur": "Thursday",
+		"thurs.": "Thursday", "Tues": "Tuesday", "星期一": "Monday", "週五": "Friday",
+		"星期日": "Sunday", "星期天": "Sunday",
+	}
+	for input, expected := range cases {
+		if day, ok := ParseDay(input); !ok || day != expected {
+			t.Errorf("ParseDay(%q): expected %s, got %s (%v)", input, expected, day, ok)
+		}
+	}
+	for _, input := range []string{"", "Mo", "Funday", "星期八"} {
+		if _, ok := ParseDay(input); ok {
+			t.Errorf("ParseDay(%q): expected invalid", input)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	cases := map[string]string{
+		"14:30": "14:30", "8:05": "08:05", "14.30": "14:30", "9": "09:00", "2pm": "14:00",
+		"2:30 PM": "14:30", "12am": "00:00", "12pm": "12:00", "11.15 a.m.": "11:15", "24:00": "24:00",
+	}
+	for input, expected := range cases {
+		if clock, ok := ParseClock(input); !ok || clock != expected {
+			t.Errorf("ParseClock(%q): expected %s, got %s (%v)", input, expected, clock, ok)
+		}
+	}
+	for _, input := range []string{"", "25:00", "24:30", "13pm", "0am", "14:60", "noon"} {
+		if _, ok := ParseClock(input); ok {
+			t.Errorf("ParseClock(%q): expected invalid", input)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package schedule
+
+import (
+	"PhantomBE/global"
+	"fmt"
+	"sort"
+)
+
+// Change describes one adjustment Normalize made to a weekly schedule
+type Change struct {
+	Action  string         `json:"action"` // merged, split_overnight, dropped_empty or dropped_invalid
+	Day     string         `json:"day"`
+	Before  []global.Shift `json:"before"`
+	After   []global.Shift `json:"after,omitempty"` // the shifts replacing Before on Day
+	Message string         `json:"message"`
+}
+
+// Normalize returns the opening hours in canonical form together with what it
+// changed: every shift opens before it closes within its own day, so shifts
+// running past midnight are split at "24:00" and continue at "00:00" on the
+// next day; zero-length shifts and unreadable days or times are dropped; and
+// duplicate, overlapping or touching shifts of a day are merged. The result is
+// sorted Monday first by open time.
+func Normalize(hours []global.OpeningHour) ([]global.OpeningHour, []Change) {
+	var changes []Change
+	spans := make(map[string][]span)
+
+	for _, hour := range hours {
+		original := global.Shift{OpenTime: hour.OpenTime, CloseTime: hour.CloseTime}
+		day, ok := ParseDay(hour.DayOfWeek)
+		start, okStart := minutes(hour.OpenTime)
+		end, okEnd := minutes(hour.CloseTime)
+		if !ok || !okStart || !okEnd || start == 24*60 {
+			changes = append(changes, Change{
+				Action:  "dropped_invalid",
+				Day:     hour.DayOfWeek,
+				Before:  []global.Shift{original},
+				Message: fmt.Sprintf("%s %s - %s is not a valid shift", hour.DayOfWeek, hour.OpenTime, hour.CloseTime),
+			})
+			continue
+		}
+
+		switch {
+		case start == end:
+			changes = append(changes, Change{
+				Action:  "dropped_empty",
+				Day:     day,
+				Before:  []global.Shift{original},
+				Message: fmt.Sprintf("%s %s - %s has zero length", day, hour.OpenTime, hour.CloseTime),
+			})
+		case end < start:
+			next := global.Days[(indexOf(global.Days, day)+1)%len(global.Days)]
+			today := span{Start: start, End: 24 * 60}
+			spans[day] = append(spans[day], today)
+			message := fmt.Sprintf("%s %s - %s runs past midnight, split into %s %s - 24:00", day, hour.OpenTime, hour.CloseTime, day, hour.OpenTime)
+			if end > 0 {
+				spans[next] = append(spans[next], span{Start: 0, End: end})
+				message += fmt.Sprintf(" and %s 00:00 - %s", next, hour.CloseTime)
+			}
+			changes = append(changes, Change{
+				Action:  "split_overnight",
+				Day:     day,
+				Before:  []global.Shift{original},
+				After:   []global.Shift{today.shift()},
+				Message: message,
+			})
+		default:
+			spans[day] = append(spans[day], span{Start: start, End: end})
+		}
+	}
+
+	var result []global.OpeningHour
+	for _, day := range global.Days {
+		merged, mergeChanges := mergeDay(day, spans[day])
+		changes = append(changes, mergeChanges...)
+		for _, s := range merged {
+			result = append(result, global.OpeningHour{
+				DayOfWeek: day,
+				OpenTime:  clock(s.Start),
+				CloseTime: clock(s.End),
+			})
+		}
+	}
+	return result, changes
+}
+
+// NormalizeShifts sorts the shifts of one override date, labelled day in the
+// changes, dropping zero-length or unreadable shifts and merging duplicate,
+// overlapping or touching ones. Shifts may run past midnight.
+func NormalizeShifts(day string, shifts []global.Shift) ([]global.Shift, []Change) {
+	var changes []Change
+	var spans []span
+	for _, shift := range shifts {
+		s, ok := toSpan(shift.OpenTime, shift.CloseTime)
+		switch {
+		case !ok || s.Start == 24*60:
+			changes = append(changes, Change{
+				Action:  "dropped_invalid",
+				Day:     day,
+				Before:  []global.Shift{shift},
+				Message: fmt.Sprintf("%s %s - %s is not a valid shift", day, shift.OpenTime, shift.CloseTime),
+			})
+		case s.Start == s.End:
+			changes = append(changes, Change{
+				Action:  "dropped_empty",
+				Day:     day,
+				Before:  []global.Shift{shift},
+				Message: fmt.Sprintf("%s %s - %s has zero length", day, shift.OpenTime, shift.CloseTime),
+			})
+		default:
+			spans = append(spans, s)
+		}
+	}
+
+	merged, mergeChanges := mergeDay(day, spans)
+	result := make([]global.Shift, len(merged))
+	for i, s := range merged {
+		result[i] = s.shift()
+	}
+	return result, append(changes, mergeChanges...)
+}
+
+// mergeDay sorts the shifts of a day and merges those that overlap or touch,
+// reporting each merge
+func mergeDay(day string, spans []span) ([]span, []Change) {
+	sorted := append([]span(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+		return sorted[i].End < sorted[j].End
+	})
+
+	var result []span
+	var changes []Change
+	for i := 0; i < len(sorted); {
+		merged := sorted[i]
+		j := i + 1
+		for j < len(sorted) && sorted[j].Start <= merged.End {
+			merged.End = max(merged.End, sorted[j].End)
+			j++
+		}
+		if j-i > 1 {
+			before := make([]global.Shift, 0, j-i)
+			for _, s := range sorted[i:j] {
+				before = append(before, s.shift())
+			}
+			changes = append(changes, Change{
+				Action:  "merged",
+				Day:     day,
+				Before:  before,
+				After:   []global.Shift{merged.shift()},
+				Message: fmt.Sprintf("%s: %d overlapping shifts merged into %s - %s", day, j-i, clock(merged.Start), clock(merged.End)),
+			})
+		}
+		result = append(result, merged)
+		i = j
+	}
+	return result, changes
+}
@@ -0,0 +1,60 @@
+package schedule
+
+import (
+	"PhantomBE/global"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	hours := ParseOpeningHours("Mon - Fri 08:00 - 17:00 / Mon 12:00 - 20:00 / Sat 20:00 - 02:00 / Sun 09:00 - 09:00")
+	hours = append(hours, global.OpeningHour{DayOfWeek: "Tuesday", OpenTime: "08:00", CloseTime: "17:00"})
+
+	result, changes := Normalize(hours)
+	expected := []global.OpeningHour{
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "20:00"},
+		{DayOfWeek: "Tuesday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Wednesday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Thursday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Friday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Saturday", OpenTime: "20:00", CloseTime: "24:00"},
+		{DayOfWeek: "Sunday", OpenTime: "00:00", CloseTime: "02:00"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	actions := map[string]int{}
+	for _, change := range changes {
+		actions[change.Action]++
+	}
+	expectedActions := map[string]int{"merged": 2, "split_overnight": 1, "dropped_empty": 1}
+	if !reflect.DeepEqual(actions, expectedActions) {
+		t.Errorf("Expected changes %v, got %v", expectedActions, changes)
+	}
+
+	// Normalized hours are left as they are
+	if again, changes := Normalize(result); !reflect.DeepEqual(again, result) || len(changes) != 0 {
+		t.Errorf("Expected normalized hours to be stable, got %v %v", again, changes)
+	}
+}
+
+func TestNormalizeShifts(t *testing.T) {
+	shifts := []global.Shift{
+		{OpenTime: "18:00", CloseTime: "02:00"},
+		{OpenTime: "08:00", CloseTime: "12:00"},
+		{OpenTime: "11:00", CloseTime: "13:00"},
+		{OpenTime: "09:00", CloseTime: "09:00"},
+	}
+	result, changes := NormalizeShifts("2025-12-24", shifts)
+	expected := []global.Shift{
+		{OpenTime: "08:00", CloseTime: "13:00"},
+		{OpenTime: "18:00", CloseTime: "02:00"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Expected a drop and a merge, got %v", changes)
+	}
+}
@@ -0,0 +1,86 @@
+package schedule
+
+import(
+	"regexp"
+	"strings"
+	"PhantomBE/global"
+)
+
+// ParseOpeningHours parses the raw opening hours of the pharmacy data, e.g.
+// "Mon, Wed 08:00 - 12:00 / Tue 14:00 - 18:00", into one entry per day.
+// Days and times may be written in any form ParseDay and ParseClock accept,
+// e.g. "Thur 2pm - 6pm" or "星期一至星期五 08.00 - 17.00".
+func ParseOpeningHours(raw string) []global.OpeningHour{
+	var result []global.OpeningHour
+	segments := strings.Split(raw, "/")
+
+	for _, segment := range segments {
+	segment = strings.TrimSpace(segment)
+	matches := openingHoursPattern.FindAllStringSubmatch(segment, -1)
+		for _, match := range matches {
+			dayExpr := match[1]
+			startTime, ok := ParseClock(match[2])
+			if !ok {
+				continue
+			}
+			endTime, ok := ParseClock(match[3])
+			if !ok {
+				continue
+			}
+			days := expandDays(dayExpr)
+			for _, day := range days {
+				result = append(result, global.OpeningHour{
+					DayOfWeek: day,
+					OpenTime:  startTime,
+					CloseTime: endTime,
+				})
+			}
+		}
+	}
+	return result
+}
+
+var openingHoursPattern = regexp.MustCompile(`(?i)((?:mon|tue|wed|thu|fri|sat|sun|星期|週|周|禮拜|礼拜)[^\d/]*?)\s*:?(\d{1,2}(?:[:.]\d{2})?\s*(?:am|pm)?)\s*(?:[-–~～]|to|至|到)+\s*(\d{1,2}(?:[:.]\d{2})?\s*(?:am|pm)?)`)
+
+// daySeparators turns the list and range separators of Chinese day
+// expressions into "," and "-"
+var daySeparators = strings.NewReplacer("、", ",", "，", ",", "至", "-", "到", "-", "~", "-", "～", "-", "–", "-")
+
+// expandDays lists the days of an expression such as "Mon - Wed, Fri"; ranges wrap around Sunday
+func expandDays(dayExpr string) []string {
+	var result []string
+	parts := strings.Split(daySeparators.Replace(dayExpr), ",")
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if strings.Contains(part, "-") {
+			bounds := strings.Split(part, "-")
+			if len(bounds) == 2 {
+				startFull, okStart := ParseDay(bounds[0])
+				endFull, okEnd := ParseDay(bounds[1])
+				startIdx := indexOf(global.Days, startFull)
+				endIdx := indexOf(global.Days, endFull)
+				if okStart && okEnd {
+					for i := startIdx; ; i = (i + 1) % len(global.Days) {
+						result = append(result, global.Days[i])
+						if i == endIdx {
+							break
+						}
+					}
+				}
+			}
+		} else if full, ok := ParseDay(part); ok {
+			result = append(result, full)
+		}
+	}
+	return result
+}
+
+func indexOf(slice []string, val string) int {
+	for i, v := range slice {
+		if v == val {
+			return i
+		}
+	}
+	return -1
+}
+
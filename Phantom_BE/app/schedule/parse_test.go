@@ -0,0 +1,45 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"PhantomBE/global"
+)
+
+func TestParseOpeningHours(t *testing.T) {
+	raw := "Mon, Wed 08:00 - 12:00 / Tue 14:00 - 18:00"
+	expected := []global.OpeningHour{
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "12:00"},
+		{DayOfWeek: "Wednesday", OpenTime: "08:00", CloseTime: "12:00"},
+		{DayOfWeek: "Tuesday", OpenTime: "14:00", CloseTime: "18:00"},
+	}
+
+	result := ParseOpeningHours(raw)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestParseOpeningHoursEndOfDay(t *testing.T) {
+	expected := []global.OpeningHour{
+		{DayOfWeek: "Saturday", OpenTime: "20:00", CloseTime: "24:00"},
+		{DayOfWeek: "Sunday", OpenTime: "00:00", CloseTime: "02:00"},
+	}
+	result := ParseOpeningHours("Sat 20:00 - 24:00 / Sun 00:00 - 02:00")
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestParseOpeningHoursLocalized(t *testing.T) {
+	expected := []global.OpeningHour{
+		{DayOfWeek: "Thursday", OpenTime: "14:00", CloseTime: "18:30"},
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Tuesday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Wednesday", OpenTime: "08:00", CloseTime: "17:00"},
+	}
+	result := ParseOpeningHours("Thur 2pm - 6.30pm / 星期一至星期三 08.00 - 17.00")
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
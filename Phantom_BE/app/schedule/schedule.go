@@ -0,0 +1,181 @@
+package schedule
+
+import (
+	"PhantomBE/global"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schedule.go holds the opening-hours rules shared by the API and the purchase path.
+// Shifts whose close time is earlier than their open time run past midnight,
+// e.g. Friday 20:00 - 02:00 is open until 02:00 on Saturday.
+
+// PreviousDay returns the day of the week before day (e.g. "Monday" -> "Sunday")
+func PreviousDay(day string) string {
+	for i, d := range global.Days {
+		if strings.EqualFold(d, day) {
+			return global.Days[(i+len(global.Days)-1)%len(global.Days)]
+		}
+	}
+	return ""
+}
+
+// Interval is one concrete opening, from Start to End inclusive
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t falls within the interval (bounds included)
+func (i Interval) Contains(t time.Time) bool {
+	return !t.Before(i.Start) && !t.After(i.End)
+}
+
+// Calendar is a pharmacy's weekly hours together with its date-specific
+// exceptions. A date with an override opens only for the override's shifts;
+// otherwise a holiday closes it; otherwise the weekly hours apply. Shifts
+// belong to the date they open on, so a closure does not cut short the
+// previous evening's shift running past midnight.
+type Calendar struct {
+	Hours     []global.OpeningHour
+	Overrides map[string]global.OpeningOverride // by "YYYY-MM-DD"
+	Holidays  map[string]string                 // observed holiday names by "YYYY-MM-DD"
+}
+
+// Horizon is how far ahead of a moment ClosesAt and NextOpening look
+const Horizon = 8 * 24 * time.Hour
+
+// Window returns the first and last dates ("YYYY-MM-DD") whose overrides and
+// holidays matter for queries between from and to
+func Window(from, to time.Time) (string, string) {
+	return from.AddDate(0, 0, -1).Format("2006-01-02"), to.Add(Horizon).Format("2006-01-02")
+}
+
+// shiftsOn returns the shifts opening on date
+func (c Calendar) shiftsOn(date time.Time) []global.Shift {
+	key := date.Format("2006-01-02")
+	if override, ok := c.Overrides[key]; ok {
+		if override.Closed {
+			return nil
+		}
+		return override.Shifts
+	}
+	if _, ok := c.Holidays[key]; ok {
+		return nil
+	}
+	var shifts []global.Shift
+	for _, hour := range c.Hours {
+		if strings.EqualFold(hour.DayOfWeek, date.Weekday().String()) {
+			shifts = append(shifts, global.Shift{OpenTime: hour.OpenTime, CloseTime: hour.CloseTime})
+		}
+	}
+	return shifts
+}
+
+// Occurrences expands the calendar into the concrete openings overlapping
+// [from, to], ordered by start, in from's location.
+func (c Calendar) Occurrences(from, to time.Time) []Interval {
+	loc := from.Location()
+	to = to.In(loc)
+	var result []Interval
+	// Start a day early to pick up shifts running past midnight into from
+	date := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -1)
+	for !date.After(to) {
+		for _, shift := range c.shiftsOn(date) {
+			start, ok := clockOn(date, shift.OpenTime)
+			if !ok {
+				continue
+			}
+			end, ok := clockOn(date, shift.CloseTime)
+			if !ok {
+				continue
+			}
+			if end.Before(start) {
+				end = end.AddDate(0, 0, 1)
+			}
+			if !end.Before(from) && !start.After(to) {
+				result = append(result, Interval{Start: start, End: end})
+			}
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result
+}
+
+// IsOpen reports whether one of the shifts covers t
+func (c Calendar) IsOpen(t time.Time) bool {
+	for _, interval := range c.Occurrences(t, t) {
+		if interval.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosesAt returns when the pharmacy open at t closes again, following shifts
+// that start before the previous one ends. It reports false when closed at t.
+func (c Calendar) ClosesAt(t time.Time) (time.Time, bool) {
+	var end time.Time
+	found := false
+	for _, interval := range c.Occurrences(t, t.Add(Horizon)) {
+		switch {
+		case interval.Contains(t):
+			if !found || interval.End.After(end) {
+				end, found = interval.End, true
+			}
+		case found && !interval.Start.After(end) && interval.End.After(end):
+			end = interval.End
+		}
+	}
+	return end, found
+}
+
+// OpenThroughout reports whether the shifts cover all of [from, to] without a
+// break; back-to-back shifts, also across midnight, count as one opening.
+func (c Calendar) OpenThroughout(from, to time.Time) bool {
+	closesAt, open := c.ClosesAt(from)
+	return open && !closesAt.Before(to)
+}
+
+// OpenDuring reports whether the shifts cover any moment of [from, to]
+func (c Calendar) OpenDuring(from, to time.Time) bool {
+	return len(c.Occurrences(from, to)) > 0
+}
+
+// NextOpening returns the earliest time after t at which one of the shifts
+// opens, in t's location. It reports false when nothing opens within Horizon.
+func (c Calendar) NextOpening(t time.Time) (time.Time, bool) {
+	for _, interval := range c.Occurrences(t, t.Add(Horizon)) {
+		if interval.Start.After(t) {
+			return interval.Start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextAt returns the next moment at or after the start of now's day that falls
+// on day at hhmm, e.g. the coming Monday 08:00 (today's date when now is Monday).
+func NextAt(day, hhmm string, now time.Time) (time.Time, bool) {
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for offset := 0; offset < len(global.Days); offset++ {
+		candidate := date.AddDate(0, 0, offset)
+		if strings.EqualFold(candidate.Weekday().String(), day) {
+			return clockOn(candidate, hhmm)
+		}
+	}
+	return time.Time{}, false
+}
+
+// clockOn places an "HH:MM" clock time on date; "24:00" is midnight at the end of date
+func clockOn(date time.Time, hhmm string) (time.Time, bool) {
+	if hhmm == "24:00" {
+		return date.AddDate(0, 0, 1), true
+	}
+	clock, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, date.Location()), true
+}
@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	"PhantomBE/global"
+)
+
+func TestPreviousDay(t *testing.T) {
+	if day := PreviousDay("Monday"); day != "Sunday" {
+		t.Errorf("Expected Sunday, got %s", day)
+	}
+	if day := PreviousDay("friday"); day != "Thursday" {
+		t.Errorf("Expected Thursday, got %s", day)
+	}
+}
+
+func TestNextOpening(t *testing.T) {
+	hours := []global.OpeningHour{
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "12:00"},
+		{DayOfWeek: "Friday", OpenTime: "20:00", CloseTime: "02:00"},
+	}
+	// Monday 2025-06-02
+	cases := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{"SameDay", time.Date(2025, 6, 2, 3, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 8, 0, 0, 0, time.UTC)},
+		{"LaterInWeek", time.Date(2025, 6, 2, 13, 0, 0, 0, time.UTC), time.Date(2025, 6, 6, 20, 0, 0, 0, time.UTC)},
+		{"NextWeek", time.Date(2025, 6, 7, 1, 0, 0, 0, time.UTC), time.Date(2025, 6, 9, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		result, ok := Calendar{Hours: hours}.NextOpening(tc.now)
+		if !ok || !result.Equal(tc.expected) {
+			t.Errorf("%s: expected %v, got %v (%v)", tc.name, tc.expected, result, ok)
+		}
+	}
+
+	if _, ok := (Calendar{}).NextOpening(time.Now()); ok {
+		t.Error("Expected no opening for empty hours")
+	}
+}
+
+func TestClosesAt(t *testing.T) {
+	hours := []global.OpeningHour{
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "12:00"},
+		{DayOfWeek: "Monday", OpenTime: "12:00", CloseTime: "18:00"},
+		{DayOfWeek: "Friday", OpenTime: "20:00", CloseTime: "02:00"},
+	}
+	cases := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+		open     bool
+	}{
+		{"ChainedShifts", time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC), true},
+		{"Overnight", time.Date(2025, 6, 7, 1, 0, 0, 0, time.UTC), time.Date(2025, 6, 7, 2, 0, 0, 0, time.UTC), true},
+		{"Closed", time.Date(2025, 6, 2, 19, 0, 0, 0, time.UTC), time.Time{}, false},
+	}
+	for _, tc := range cases {
+		result, ok := Calendar{Hours: hours}.ClosesAt(tc.now)
+		if ok != tc.open || !result.Equal(tc.expected) {
+			t.Errorf("%s: expected %v (%v), got %v (%v)", tc.name, tc.expected, tc.open, result, ok)
+		}
+		if (Calendar{Hours: hours}).IsOpen(tc.now) != tc.open {
+			t.Errorf("%s: expected open=%v", tc.name, tc.open)
+		}
+	}
+}
+
+func TestNextAt(t *testing.T) {
+	// Wednesday 2025-06-04
+	now := time.Date(2025, 6, 4, 15, 0, 0, 0, time.UTC)
+	result, ok := NextAt("Monday", "08:00", now)
+	if !ok || !result.Equal(time.Date(2025, 6, 9, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next Monday 08:00, got %v (%v)", result, ok)
+	}
+	result, ok = NextAt("Wednesday", "09:00", now)
+	if !ok || !result.Equal(time.Date(2025, 6, 4, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected today 09:00, got %v (%v)", result, ok)
+	}
+}
+
+func TestOpenInterval(t *testing.T) {
+	hours := []global.OpeningHour{
+		{DayOfWeek: "Friday", OpenTime: "08:00", CloseTime: "12:00"},
+		{DayOfWeek: "Friday", OpenTime: "13:00", CloseTime: "18:00"},
+		{DayOfWeek: "Saturday", OpenTime: "18:00", CloseTime: "24:00"},
+		{DayOfWeek: "Sunday", OpenTime: "00:00", CloseTime: "06:00"},
+	}
+	// Friday 2025-06-06
+	at := func(day, hour int) time.Time { return time.Date(2025, 6, day, hour, 0, 0, 0, time.UTC) }
+	cases := []struct {
+		name       string
+		from, to   time.Time
+		throughout bool
+		during     bool
+	}{
+		{"WithinShift", at(6, 9), at(6, 11), true, true},
+		{"AcrossLunchBreak", at(6, 10), at(6, 14), false, true},
+		{"AtClosingTime", at(6, 12), at(6, 12), true, true},
+		{"Closed", at(6, 19), at(7, 17), false, false},
+		{"AcrossMidnight", at(7, 20), at(8, 5), true, true},
+	}
+	for _, tc := range cases {
+		if got := (Calendar{Hours: hours}).OpenThroughout(tc.from, tc.to); got != tc.throughout {
+			t.Errorf("%s: expected throughout=%v, got %v", tc.name, tc.throughout, got)
+		}
+		if got := (Calendar{Hours: hours}).OpenDuring(tc.from, tc.to); got != tc.during {
+			t.Errorf("%s: expected during=%v, got %v", tc.name, tc.during, got)
+		}
+	}
+}
+
+func TestCalendarExceptions(t *testing.T) {
+	calendar := Calendar{
+		Hours: []global.OpeningHour{
+			{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "12:00"},
+			{DayOfWeek: "Tuesday", OpenTime: "08:00", CloseTime: "12:00"},
+			{DayOfWeek: "Wednesday", OpenTime: "08:00", CloseTime: "12:00"},
+		},
+		Overrides: map[string]global.OpeningOverride{
+			"2025-06-02": {Date: "2025-06-02", Closed: true},
+			"2025-06-03": {Date: "2025-06-03", Shifts: []global.Shift{{OpenTime: "18:00", CloseTime: "23:00"}}},
+			"2025-06-04": {Date: "2025-06-04", Shifts: []global.Shift{{OpenTime: "09:00", CloseTime: "10:00"}}},
+		},
+		Holidays: map[string]string{"2025-06-04": "Holiday", "2025-06-09": "Holiday"},
+	}
+	at := func(day, hour int) time.Time { return time.Date(2025, 6, day, hour, 0, 0, 0, time.UTC) }
+	cases := []struct {
+		name string
+		at   time.Time
+		open bool
+	}{
+		{"ClosedOverride", at(2, 9), false},
+		{"ReplacedHoursOld", at(3, 9), false},
+		{"ReplacedHoursNew", at(3, 19), true},
+		{"OverrideBeatsHoliday", at(4, 9), true},
+		{"Holiday", at(9, 9), false},
+		{"RegularWeek", at(10, 9), true},
+	}
+	for _, tc := range cases {
+		if got := calendar.IsOpen(tc.at); got != tc.open {
+			t.Errorf("%s: expected open=%v, got %v", tc.name, tc.open, got)
+		}
+	}
+
+	next, ok := calendar.NextOpening(at(1, 12))
+	if !ok || !next.Equal(at(3, 18)) {
+		t.Errorf("Expected next opening on the replaced hours, got %v (%v)", next, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package schedule
+
+import (
+	"PhantomBE/global"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DaySchedule is the merged, sorted shifts of one day of the week. A shift
+// closing earlier than it opens runs past midnight into the next day.
+type DaySchedule struct {
+	Day    string         `json:"day"`
+	Shifts []global.Shift `json:"shifts"`
+}
+
+// span is a shift in minutes from the start of its opening day; End passes
+// 24 * 60 for shifts running past midnight
+type span struct {
+	Start int
+	End   int
+}
+
+// Weekly groups the opening hours by day of the week, Monday first, merging
+// duplicate and overlapping shifts of the same day. Days without hours are
+// included with no shifts.
+func Weekly(hours []global.OpeningHour) []DaySchedule {
+	spans := make(map[string][]span)
+	for _, hour := range hours {
+		day, ok := ParseDay(hour.DayOfWeek)
+		if !ok {
+			continue
+		}
+		s, ok := toSpan(hour.OpenTime, hour.CloseTime)
+		if !ok {
+			continue
+		}
+		spans[day] = append(spans[day], s)
+	}
+
+	week := make([]DaySchedule, 0, len(global.Days))
+	for _, day := range global.Days {
+		schedule := DaySchedule{Day: day, Shifts: []global.Shift{}}
+		merged, _ := mergeDay(day, spans[day])
+		for _, s := range merged {
+			schedule.Shifts = append(schedule.Shifts, s.shift())
+		}
+		week = append(week, schedule)
+	}
+	return week
+}
+
+// Render writes the week in the raw format of the pharmacy data, days sharing
+// a shift listed together, e.g. "Mon, Wed, Fri 08:00 - 12:00 / Tue, Thu 14:00 - 18:00".
+// Runs of three or more consecutive days are written as a range ("Mon - Fri").
+func Render(week []DaySchedule) string {
+	type group struct {
+		shift global.Shift
+		days  []int
+	}
+	var groups []*group
+	byShift := make(map[global.Shift]*group)
+	for _, schedule := range week {
+		index := indexOf(global.Days, schedule.Day)
+		if index < 0 {
+			continue
+		}
+		for _, shift := range schedule.Shifts {
+			g, ok := byShift[shift]
+			if !ok {
+				g = &group{shift: shift}
+				byShift[shift] = g
+				groups = append(groups, g)
+			}
+			g.days = append(g.days, index)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].days[0] != groups[j].days[0] {
+			return groups[i].days[0] < groups[j].days[0]
+		}
+		return groups[i].shift.OpenTime < groups[j].shift.OpenTime
+	})
+
+	segments := make([]string, len(groups))
+	for i, g := range groups {
+		segments[i] = fmt.Sprintf("%s %s - %s", renderDays(g.days), g.shift.OpenTime, g.shift.CloseTime)
+	}
+	return strings.Join(segments, " / ")
+}
+
+// renderDays writes ascending day indexes as short names, e.g. "Mon - Wed, Fri"
+func renderDays(days []int) string {
+	var parts []string
+	for i := 0; i < len(days); {
+		j := i
+		for j+1 < len(days) && days[j+1] == days[j]+1 {
+			j++
+		}
+		switch {
+		case j-i >= 2:
+			parts = append(parts, shortDay(days[i])+" - "+shortDay(days[j]))
+		default:
+			for k := i; k <= j; k++ {
+				parts = append(parts, shortDay(days[k]))
+			}
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+func shortDay(index int) string {
+	return global.Days[index][:3]
+}
+
+// toSpan converts "HH:MM" open and close times into minutes
+func toSpan(open, close string) (span, bool) {
+	start, ok := minutes(open)
+	if !ok {
+		return span{}, false
+	}
+	end, ok := minutes(close)
+	if !ok {
+		return span{}, false
+	}
+	if end < start {
+		end += 24 * 60
+	}
+	return span{Start: start, End: end}, true
+}
+
+func (s span) shift() global.Shift {
+	return global.Shift{OpenTime: clock(s.Start), CloseTime: clock(s.End)}
+}
+
+// minutes parses "HH:MM" (or "24:00") into minutes after midnight
+func minutes(hhmm string) (int, bool) {
+	if hhmm == "24:00" {
+		return 24 * 60, true
+	}
+	var hour, minute int
+	if _, err := fmt.Sscanf(hhmm, "%d:%d", &hour, &minute); err != nil || hour > 23 || minute > 59 || hour < 0 || minute < 0 {
+		return 0, false
+	}
+	return hour*60 + minute, true
+}
+
+// clock formats minutes after midnight as "HH:MM"; the end of the day is "24:00"
+// and later minutes wrap into the next day
+func clock(m int) string {
+	if m == 24*60 {
+		return "24:00"
+	}
+	m %= 24 * 60
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
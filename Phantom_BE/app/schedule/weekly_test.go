@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"PhantomBE/global"
+	"reflect"
+	"testing"
+)
+
+func TestWeekly(t *testing.T) {
+	hours := []global.OpeningHour{
+		{DayOfWeek: "Monday", OpenTime: "12:00", CloseTime: "20:00"},
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Monday", OpenTime: "08:00", CloseTime: "17:00"},
+		{DayOfWeek: "Tuesday", OpenTime: "20:00", CloseTime: "02:00"},
+		{DayOfWeek: "Tuesday", OpenTime: "08:00", CloseTime: "12:00"},
+	}
+
+	week := Weekly(hours)
+	if len(week) != 7 {
+		t.Fatalf("Expected 7 days, got %d", len(week))
+	}
+	expectedMonday := []global.Shift{{OpenTime: "08:00", CloseTime: "20:00"}}
+	if !reflect.DeepEqual(week[0].Shifts, expectedMonday) {
+		t.Errorf("Expected %v, got %v", expectedMonday, week[0].Shifts)
+	}
+	expectedTuesday := []global.Shift{{OpenTime: "08:00", CloseTime: "12:00"}, {OpenTime: "20:00", CloseTime: "02:00"}}
+	if !reflect.DeepEqual(week[1].Shifts, expectedTuesday) {
+		t.Errorf("Expected %v, got %v", expectedTuesday, week[1].Shifts)
+	}
+	if len(week[2].Shifts) != 0 {
+		t.Errorf("Expected Wednesday closed, got %v", week[2].Shifts)
+	}
+}
+
+func TestRender(t *testing.T) {
+	raw := "Mon, Wed, Fri 08:00 - 12:00 / Tue, Thu 14:00 - 18:00"
+	if text := Render(Weekly(ParseOpeningHours(raw))); text != raw {
+		t.Errorf("Expected %q, got %q", raw, text)
+	}
+
+	raw = "Mon - Fri 08:00 - 17:00 / Sat, Sun 10:00 - 14:00"
+	text := Render(Weekly(ParseOpeningHours(raw)))
+	if text != raw {
+		t.Errorf("Expected %q, got %q", raw, text)
+	}
+	// The rendering parses back into the same schedule
+	if !reflect.DeepEqual(Weekly(ParseOpeningHours(text)), Weekly(ParseOpeningHours(raw))) {
+		t.Errorf("Rendering %q does not parse back", text)
+	}
+}
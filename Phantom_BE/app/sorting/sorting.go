@@ -0,0 +1,159 @@
+package sorting
+
+import (
+	"errors"
+	"strings"
+)
+
+// sorting.go parses the sort specs of list requests, e.g. "price desc, name"
+// or "-price,name", against the keys an endpoint whitelists, and turns them
+// into ORDER BY clauses and keyset conditions on the SQL expressions of the keys.
+
+var ErrInvalidSpec = errors.New("invalid sort spec")
+
+// Key is one key of a sort spec, Order is "asc" or "desc"
+type Key struct {
+	Field string
+	Order string
+}
+
+// Spec is the keys of a sort, most significant first
+type Spec []Key
+
+// Parse reads a comma-separated list of keys, each "field", "field asc",
+// "field desc", "-field" or "+field". Keys without a direction take order
+// ("asc" when empty). Fields must be in allowed and appear once.
+func Parse(spec, order string, allowed []string) (Spec, error) {
+	if order == "" {
+		order = "asc"
+	}
+	if order != "asc" && order != "desc" {
+		return nil, ErrInvalidSpec
+	}
+
+	var keys Spec
+	for _, part := range strings.Split(spec, ",") {
+		words := strings.Fields(strings.ToLower(part))
+		if len(words) == 0 || len(words) > 2 {
+			return nil, ErrInvalidSpec
+		}
+		key := Key{Field: words[0], Order: order}
+		switch {
+		case strings.HasPrefix(key.Field, "-"):
+			key.Field, key.Order = key.Field[1:], "desc"
+		case strings.HasPrefix(key.Field, "+"):
+			key.Field, key.Order = key.Field[1:], "asc"
+		}
+		if len(words) == 2 {
+			if words[1] != "asc" && words[1] != "desc" || key.Field != words[0] {
+				return nil, ErrInvalidSpec
+			}
+			key.Order = words[1]
+		}
+		if !contains(allowed, key.Field) || keys.has(key.Field) {
+			return nil, ErrInvalidSpec
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// String is the canonical form of the spec, e.g. "price desc, name asc"
+func (s Spec) String() string {
+	parts := make([]string, len(s))
+	for i, key := range s {
+		parts[i] = key.Field + " " + key.Order
+	}
+	return strings.Join(parts, ", ")
+}
+
+// OrderBy is the ORDER BY clause of the spec on the given key expressions,
+// with ties broken by idColumn in the direction of the first key
+func (s Spec) OrderBy(columns map[string]string, idColumn string) string {
+	parts := make([]string, 0, len(s)+1)
+	for _, key := range s {
+		parts = append(parts, columns[key.Field]+" "+key.Order)
+	}
+	parts = append(parts, idColumn+" "+s.idOrder())
+	return strings.Join(parts, ", ")
+}
+
+// After is the condition selecting the rows that follow the row with the
+// given key values and ID in the order of OrderBy
+func (s Spec) After(columns map[string]string, idColumn string, values []interface{}, id uint) (string, []interface{}) {
+	expressions := make([]string, 0, len(s)+1)
+	orders := make([]string, 0, len(s)+1)
+	for _, key := range s {
+		expressions = append(expressions, columns[key.Field])
+		orders = append(orders, key.Order)
+	}
+	expressions = append(expressions, idColumn)
+	orders = append(orders, s.idOrder())
+	args := append(append([]interface{}{}, values...), id)
+
+	// A single direction is one row comparison, which indexes can serve
+	if s.uniform() {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+		return "(" + strings.Join(expressions, ", ") + ") " + operator(orders[0]) + " (" + placeholders + ")", args
+	}
+
+	// Otherwise equal on the leading keys and past the value of the next one
+	var clauses []string
+	var clauseArgs []interface{}
+	for i := range expressions {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, expressions[j]+" = ?")
+			clauseArgs = append(clauseArgs, args[j])
+		}
+		terms = append(terms, expressions[i]+" "+operator(orders[i])+" ?")
+		clauseArgs = append(clauseArgs, args[i])
+		clauses = append(clauses, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", clauseArgs
+}
+
+// has reports whether the spec sorts by field
+func (s Spec) has(field string) bool {
+	for _, key := range s {
+		if key.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// idOrder is the direction of the ID tie-breaker
+func (s Spec) idOrder() string {
+	if len(s) == 0 {
+		return "asc"
+	}
+	return s[0].Order
+}
+
+// uniform reports whether every key sorts in the same direction
+func (s Spec) uniform() bool {
+	for _, key := range s {
+		if key.Order != s.idOrder() {
+			return false
+		}
+	}
+	return true
+}
+
+// operator compares past a value in the given direction
+func operator(order string) string {
+	if order == "desc" {
+		return "<"
+	}
+	return ">"
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
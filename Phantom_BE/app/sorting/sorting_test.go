@@ -0,0 +1,62 @@
+package sorting
+
+import (
+	"reflect"
+	"testing"
+)
+
+var allowed = []string{"name", "price", "pack_size", "popularity"}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec, order string
+		expected    string
+	}{
+		{"name", "", "name asc"},
+		{"price", "desc", "price desc"},
+		{"price desc, name asc", "", "price desc, name asc"},
+		{"-popularity,name", "", "popularity desc, name asc"},
+		{" Price DESC ,+pack_size", "desc", "price desc, pack_size asc"},
+		{"price,name", "desc", "price desc, name desc"},
+	}
+	for _, test := range tests {
+		spec, err := Parse(test.spec, test.order, allowed)
+		if err != nil || spec.String() != test.expected {
+			t.Errorf("%q %q: expected %q, got %q %v", test.spec, test.order, test.expected, spec, err)
+		}
+	}
+
+	for _, invalid := range []string{"", "color", "price,price", "price up", "-price desc", "name,", "price desc asc", "-"} {
+		if _, err := Parse(invalid, "", allowed); err != ErrInvalidSpec {
+			t.Errorf("%q: expected ErrInvalidSpec, got %v", invalid, err)
+		}
+	}
+	if _, err := Parse("name", "up", allowed); err != ErrInvalidSpec {
+		t.Errorf("Expected ErrInvalidSpec for order up, got %v", err)
+	}
+}
+
+var columns = map[string]string{"name": "masks.name", "price": "masks.price"}
+
+func TestOrderBy(t *testing.T) {
+	spec, _ := Parse("price desc, name", "", allowed)
+	if clause := spec.OrderBy(columns, "masks.id"); clause != "masks.price desc, masks.name asc, masks.id desc" {
+		t.Errorf("Unexpected clause %q", clause)
+	}
+}
+
+func TestAfter(t *testing.T) {
+	spec, _ := Parse("price,name", "desc", allowed)
+	condition, args := spec.After(columns, "masks.id", []interface{}{9.5, "A"}, 7)
+	if condition != "(masks.price, masks.name, masks.id) < (?, ?, ?)" || !reflect.DeepEqual(args, []interface{}{9.5, "A", uint(7)}) {
+		t.Errorf("Unexpected condition %q %v", condition, args)
+	}
+
+	spec, _ = Parse("price desc, name", "", allowed)
+	condition, args = spec.After(columns, "masks.id", []interface{}{9.5, "A"}, 7)
+	expected := "((masks.price < ?) OR (masks.price = ? AND masks.name > ?) OR (masks.price = ? AND masks.name = ? AND masks.id < ?))"
+	expectedArgs := []interface{}{9.5, 9.5, "A", 9.5, "A", uint(7)}
+	if condition != expected || !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected %q %v, got %q %v", expected, expectedArgs, condition, args)
+	}
+}
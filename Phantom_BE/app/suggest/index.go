@@ -0,0 +1,217 @@
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// index.go keeps the names that can be suggested in memory, so completions
+// are fast enough to be requested on every keystroke.
+
+// Entry is a name that can be suggested
+type Entry struct {
+	Type string `json:"type"`         // pharmacy, mask or brand
+	ID   uint   `json:"id,omitempty"` // pharmacy or product ID, none for brands
+	Text string `json:"text"`
+}
+
+// Suggestion is an entry completing a query
+type Suggestion struct {
+	Entry
+	Match string  `json:"match"` // prefix or fuzzy
+	Score float64 `json:"score"`
+}
+
+// typeRank orders suggestions that tie on score
+var typeRank = map[string]int{"pharmacy": 0, "brand": 1, "mask": 2}
+
+// key is a lower-cased suffix of an entry's name starting at a word
+type key struct {
+	term  string
+	entry int
+	word  int // 0 when the term is the whole name
+}
+
+// Index answers prefix and fuzzy lookups over a set of entries. It is safe
+// for concurrent use; Replace swaps in a new set atomically.
+type Index struct {
+	mu      sync.RWMutex
+	entries []Entry
+	keys    []key // sorted by term
+	builtAt time.Time
+	stale   chan struct{}
+}
+
+// Default is the index served by the suggest API
+var Default = NewIndex()
+
+func NewIndex() *Index {
+	return &Index{stale: make(chan struct{}, 1)}
+}
+
+// Replace swaps the indexed entries for entries
+func (ix *Index) Replace(entries []Entry) {
+	var keys []key
+	for i, entry := range entries {
+		words := strings.Fields(normalize(entry.Text))
+		for w := range words {
+			keys = append(keys, key{term: strings.Join(words[w:], " "), entry: i, word: w})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].term < keys[j].term })
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries = entries
+	ix.keys = keys
+	ix.builtAt = time.Now()
+}
+
+// BuiltAt is when the entries were last replaced
+func (ix *Index) BuiltAt() time.Time {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.builtAt
+}
+
+// MarkStale asks the refresher to reload the entries after a data change
+func (ix *Index) MarkStale() {
+	select {
+	case ix.stale <- struct{}{}:
+	default: // a reload is already pending
+	}
+}
+
+// Lookup returns up to limit entries of the given types (all when empty)
+// whose name or one of its words starts with query, best first. Fuzzy
+// matches within a small edit distance fill the rest for queries of 4 or
+// more characters.
+func (ix *Index) Lookup(query string, types []string, limit int) []Suggestion {
+	q := normalize(query)
+	if q == "" || limit <= 0 {
+		return []Suggestion{}
+	}
+	allowed := func(entry Entry) bool {
+		if len(types) == 0 {
+			return true
+		}
+		for _, t := range types {
+			if t == entry.Type {
+				return true
+			}
+		}
+		return false
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	best := make(map[int]Suggestion)
+	offer := func(k key, match string, score float64) {
+		entry := ix.entries[k.entry]
+		if !allowed(entry) {
+			return
+		}
+		if current, ok := best[k.entry]; ok && current.Score >= score {
+			return
+		}
+		best[k.entry] = Suggestion{Entry: entry, Match: match, Score: math.Round(score*100) / 100}
+	}
+
+	// Prefix matches are contiguous in the sorted keys
+	start := sort.Search(len(ix.keys), func(i int) bool { return ix.keys[i].term >= q })
+	for i := start; i < len(ix.keys) && strings.HasPrefix(ix.keys[i].term, q); i++ {
+		k := ix.keys[i]
+		base := 100.0
+		if k.word > 0 {
+			base = 90
+		}
+		offer(k, "prefix", base-lengthPenalty(ix.entries[k.entry].Text, q))
+	}
+
+	queryLength := utf8.RuneCountInString(q)
+	if len(best) < limit && queryLength >= 4 {
+		maxEdits := 1
+		if queryLength >= 6 {
+			maxEdits = 2
+		}
+		for _, k := range ix.keys {
+			if best[k.entry].Match == "prefix" {
+				continue
+			}
+			if edits, ok := prefixDistance(q, k.term, maxEdits); ok && edits > 0 {
+				offer(k, "fuzzy", 70-10*float64(edits)-lengthPenalty(ix.entries[k.entry].Text, q))
+			}
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(best))
+	for _, suggestion := range best {
+		suggestions = append(suggestions, suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return typeRank[a.Type] < typeRank[b.Type]
+		}
+		return a.Text < b.Text
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// normalize lower-cases s and collapses its whitespace
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// lengthPenalty prefers completions close to the query's length, up to 10
+func lengthPenalty(text, q string) float64 {
+	total := utf8.RuneCountInString(text)
+	if total == 0 {
+		return 0
+	}
+	missing := total - utf8.RuneCountInString(q)
+	return float64(max(missing, 0)) / float64(total) * 10
+}
+
+// prefixDistance returns the smallest edit distance between q and a prefix of
+// term, if it is at most maxEdits
+func prefixDistance(q, term string, maxEdits int) (int, bool) {
+	a, b := []rune(q), []rune(term)
+	if len(b) > len(a)+maxEdits {
+		b = b[:len(a)+maxEdits]
+	}
+	// previous[j] is the distance between the read part of a and b[:j]
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	// Any prefix of term may complete q
+	distance := previous[0]
+	for _, d := range previous {
+		distance = min(distance, d)
+	}
+	return distance, distance <= maxEdits
+}
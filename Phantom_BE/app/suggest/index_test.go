@@ -0,0 +1,96 @@
+package suggest
+
+import "testing"
+
+func testIndex() *Index {
+	ix := NewIndex()
+	ix.Replace([]Entry{
+		{Type: "pharmacy", ID: 1, Text: "Carepoint"},
+		{Type: "pharmacy", ID: 2, Text: "First Care Rx"},
+		{Type: "pharmacy", ID: 3, Text: "康是美藥局"},
+		{Type: "mask", ID: 10, Text: "True Barrier (green) (3 per pack)"},
+		{Type: "mask", ID: 11, Text: "Cotton Kiss (blue) (6 per pack)"},
+		{Type: "brand", Text: "True Barrier"},
+		{Type: "brand", Text: "Cotton Kiss"},
+	})
+	return ix
+}
+
+func texts(suggestions []Suggestion) []string {
+	result := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		result[i] = suggestion.Text
+	}
+	return result
+}
+
+func TestLookupPrefix(t *testing.T) {
+	ix := testIndex()
+
+	got := texts(ix.Lookup("car", nil, 10))
+	expected := []string{"Carepoint", "First Care Rx"}
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// Whole names rank above word matches, shorter names above longer ones
+	got = texts(ix.Lookup("  TRUE  b", nil, 10))
+	if len(got) != 2 || got[0] != "True Barrier" {
+		t.Errorf("Expected the brand first, got %v", got)
+	}
+
+	got = texts(ix.Lookup("康是", nil, 10))
+	if len(got) != 1 || got[0] != "康是美藥局" {
+		t.Errorf("Expected the Chinese name, got %v", got)
+	}
+}
+
+func TestLookupTypesAndLimit(t *testing.T) {
+	ix := testIndex()
+
+	got := ix.Lookup("cotton", []string{"brand"}, 10)
+	if len(got) != 1 || got[0].Type != "brand" || got[0].ID != 0 {
+		t.Errorf("Expected only the brand, got %+v", got)
+	}
+
+	if got := ix.Lookup("c", nil, 1); len(got) != 1 {
+		t.Errorf("Expected 1 suggestion, got %d", len(got))
+	}
+	if got := ix.Lookup("   ", nil, 10); len(got) != 0 {
+		t.Errorf("Expected no suggestions for a blank query, got %v", got)
+	}
+}
+
+func TestLookupFuzzy(t *testing.T) {
+	ix := testIndex()
+
+	got := ix.Lookup("barier", nil, 10)
+	if len(got) != 2 || got[0].Match != "fuzzy" || got[0].Text != "True Barrier" {
+		t.Errorf("Expected fuzzy matches of barrier, got %+v", got)
+	}
+
+	// Short queries only complete prefixes
+	if got := ix.Lookup("cxr", nil, 10); len(got) != 0 {
+		t.Errorf("Expected no fuzzy matches for a short query, got %+v", got)
+	}
+}
+
+func TestPrefixDistance(t *testing.T) {
+	tests := []struct {
+		q, term  string
+		maxEdits int
+		distance int
+		ok       bool
+	}{
+		{"care", "carepoint", 1, 0, true},
+		{"cxre", "carepoint", 1, 1, true},
+		{"crae", "carepoint", 1, 2, false},
+		{"carepint", "carepoint", 2, 1, true},
+	}
+	for _, test := range tests {
+		distance, ok := prefixDistance(test.q, test.term, test.maxEdits)
+		if ok != test.ok || (ok && distance != test.distance) {
+			t.Errorf("prefixDistance(%q, %q) = %d, %v; expected %d, %v", test.q, test.term, distance, ok, test.distance, test.ok)
+		}
+	}
+}
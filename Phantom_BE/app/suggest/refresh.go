@@ -0,0 +1,81 @@
+package suggest
+
+import (
+	"PhantomBE/global"
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gorm.io/gorm"
+)
+
+// Load reads the pharmacy names and the names and brands of the products
+// some pharmacy sells
+func Load(db *gorm.DB) ([]Entry, error) {
+	var pharmacies []global.Pharmacy
+	if err := db.Select("id, name").Order("id").Find(&pharmacies).Error; err != nil {
+		return nil, err
+	}
+	var products []global.Product
+	if err := db.Select("id, name, brand").
+		Where("EXISTS (SELECT 1 FROM masks WHERE masks.product_id = products.id)").
+		Order("id").
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(pharmacies)+len(products))
+	for _, pharmacy := range pharmacies {
+		entries = append(entries, Entry{Type: "pharmacy", ID: pharmacy.ID, Text: pharmacy.Name})
+	}
+	brands := make(map[string]bool)
+	for _, product := range products {
+		entries = append(entries, Entry{Type: "mask", ID: product.ID, Text: product.Name})
+		if product.Brand != "" && !brands[product.Brand] {
+			brands[product.Brand] = true
+			entries = append(entries, Entry{Type: "brand", Text: product.Brand})
+		}
+	}
+	return entries, nil
+}
+
+// Refresher reloads an index when it is marked stale and periodically, which
+// also picks up data imported by other processes.
+type Refresher struct {
+	db       *gorm.DB
+	index    *Index
+	interval time.Duration
+}
+
+func NewRefresher(db *gorm.DB, index *Index, interval time.Duration) *Refresher {
+	return &Refresher{db: db, index: index, interval: interval}
+}
+
+// Start loads the index and keeps it fresh in the background until ctx is cancelled
+func (r *Refresher) Start(ctx context.Context) {
+	go func() {
+		r.Refresh(ctx)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.index.stale:
+				r.Refresh(ctx)
+			case <-ticker.C:
+				r.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+// Refresh reloads the index, keeping the previous entries when loading fails
+func (r *Refresher) Refresh(ctx context.Context) {
+	entries, err := Load(r.db.WithContext(ctx))
+	if err != nil {
+		log.Error("suggest index refresh failed", "err", err)
+		return
+	}
+	r.index.Replace(entries)
+}
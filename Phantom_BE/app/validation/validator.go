@@ -2,7 +2,8 @@ package validation
 
 import (
 	"PhantomBE/app/api"
-	"PhantomBE/global"
+	"PhantomBE/app/schedule"
+	"PhantomBE/app/sorting"
 	"fmt"
 	"time"
 	"strings"
@@ -18,22 +19,30 @@ func RegisterPharmacyValidators() error {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		// --- Time and Date Validators ---
 
-		// Valid day of the week (e.g., "Monday")
+		// Valid day of the week (e.g., "Monday", "Thur", "星期一")
         if err := v.RegisterValidation("valid_day", func(fl validator.FieldLevel) bool {
-            _, valid := isValidDay(fl.Field().String())
+            _, valid := schedule.ParseDay(fl.Field().String())
             return valid
         }); err != nil {
             panic(fmt.Sprintf("Failed to register valid_day validator: %v", err))
         }
 
-		// Time in HH:MM format
+		// Time of day (e.g., "14:30", "14.30", "2pm")
         if err := v.RegisterValidation("time_format", func(fl validator.FieldLevel) bool {
-            _, err := time.Parse("15:04", fl.Field().String())
-            return err == nil
+            clock, valid := schedule.ParseClock(fl.Field().String())
+            return valid && clock != "24:00"
         }); err != nil {
             panic(fmt.Sprintf("Failed to register time_format validator: %v", err))
         }
 
+		// Closing time of day, "24:00" closes at the end of the day
+		if err := v.RegisterValidation("close_time_format", func(fl validator.FieldLevel) bool {
+			_, valid := schedule.ParseClock(fl.Field().String())
+			return valid
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register close_time_format validator: %v", err))
+		}
+
 		// Date in YYYY-MM-DD format
 		if err := v.RegisterValidation("date_format", func(fl validator.FieldLevel) bool {
 			_, err := time.Parse("2006-01-02", fl.Field().String())
@@ -42,6 +51,21 @@ func RegisterPharmacyValidators() error {
 			panic(fmt.Sprintf("Failed to register date_format validator: %v", err))
 		}
 
+		// Date and time in YYYY-MM-DD HH:MM format
+		if err := v.RegisterValidation("datetime_format", func(fl validator.FieldLevel) bool {
+			_, err := time.Parse("2006-01-02 15:04", fl.Field().String())
+			return err == nil
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register datetime_format validator: %v", err))
+		}
+
+		// Raw opening hours the pharmacy data parser understands
+		if err := v.RegisterValidation("opening_hours_format", func(fl validator.FieldLevel) bool {
+			return len(schedule.ParseOpeningHours(fl.Field().String())) > 0
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register opening_hours_format validator: %v", err))
+		}
+
 		// --- Numeric Validators ---
 
 		// Positive integers
@@ -87,8 +111,8 @@ func RegisterPharmacyValidators() error {
             if fl.Field().String() == "" {
                 return true // Allow empty, will use default
             }
-            validSorts := []string{"name", "price"}
-            return contains(validSorts, fl.Field().String())
+            _, err := sorting.Parse(fl.Field().String(), "", api.MaskSortKeys)
+            return err == nil
         }); err != nil {
             panic(fmt.Sprintf("Failed to register valid_sort validator: %v", err))
         }
@@ -104,6 +128,14 @@ func RegisterPharmacyValidators() error {
             panic(fmt.Sprintf("Failed to register valid_order validator: %v", err))
         }
 
+		// Valid price basis for filtering
+		if err := v.RegisterValidation("valid_price_basis", func(fl validator.FieldLevel) bool {
+			validBases := []string{"base", "best_tier"}
+			return contains(validBases, fl.Field().String())
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register valid_price_basis validator: %v", err))
+		}
+
 		// Valid filter operator
         if err := v.RegisterValidation("valid_operator", func(fl validator.FieldLevel) bool {
             validOperators := []string{"more", "less"}
@@ -111,6 +143,20 @@ func RegisterPharmacyValidators() error {
         }); err != nil {
             panic(fmt.Sprintf("Failed to register valid_operator validator: %v", err))
         }
+
+		// Pharmacy associations to include
+		if err := v.RegisterValidation("pharmacy_include", func(fl validator.FieldLevel) bool {
+			return validList(fl.Field().String(), api.PharmacyIncludeKeys)
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register pharmacy_include validator: %v", err))
+		}
+
+		// Pharmacy columns to select
+		if err := v.RegisterValidation("pharmacy_fields", func(fl validator.FieldLevel) bool {
+			return validList(fl.Field().String(), api.PharmacyFieldKeys)
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register pharmacy_fields validator: %v", err))
+		}
 		
 		// --- Search Query Validators ---
 
@@ -160,6 +206,50 @@ func RegisterPharmacyValidators() error {
 			panic(fmt.Sprintf("Failed to register safe_search validator: %v", err))
 		}
 
+		// --- Promotion Validators ---
+
+		// Coupon code: 3-32 letters, digits, hyphens or underscores
+		if err := v.RegisterValidation("coupon_code", func(fl validator.FieldLevel) bool {
+			code := fl.Field().String()
+			if len(code) < 3 || len(code) > 32 {
+				return false
+			}
+			for _, char := range code {
+				if !unicode.IsLetter(char) && !unicode.IsDigit(char) && char != '-' && char != '_' {
+					return false
+				}
+			}
+			return true
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register coupon_code validator: %v", err))
+		}
+
+		// Valid discount type
+		if err := v.RegisterValidation("valid_discount_type", func(fl validator.FieldLevel) bool {
+			validTypes := []string{"percentage", "fixed", "buy_x_get_y"}
+			return contains(validTypes, fl.Field().String())
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register valid_discount_type validator: %v", err))
+		}
+
+		// --- Subscription Validators ---
+
+		// Valid subscription frequency
+		if err := v.RegisterValidation("valid_frequency", func(fl validator.FieldLevel) bool {
+			validFrequencies := []string{"daily", "weekly", "monthly"}
+			return contains(validFrequencies, fl.Field().String())
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register valid_frequency validator: %v", err))
+		}
+
+		// Valid subscription status
+		if err := v.RegisterValidation("valid_subscription_status", func(fl validator.FieldLevel) bool {
+			validStatuses := []string{"active", "paused", "cancelled"}
+			return contains(validStatuses, fl.Field().String())
+		}); err != nil {
+			panic(fmt.Sprintf("Failed to register valid_subscription_status validator: %v", err))
+		}
+
 		// --- Struct-Level Validators ---
 
 		// StartDate <= EndDate and within max duration
@@ -202,6 +292,137 @@ func RegisterPharmacyValidators() error {
 		// Register the combined validation for both structs
 		v.RegisterStructValidation(dateRangeValidator, api.TopUsersRequest{})
 		v.RegisterStructValidation(dateRangeValidator, api.TransactionSummaryRequest{})
+		v.RegisterStructValidation(dateRangeValidator, api.CommissionSummaryRequest{})
+		v.RegisterStructValidation(dateRangeValidator, api.UserPurchasesRequest{})
+
+		// Open pharmacies are checked at day or date + time, now, or over from - to
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.OpenPharmaciesRequest)
+			interval := req.From != "" || req.To != ""
+			modes := 0
+			for _, set := range []bool{req.Day != "", req.Date != "", req.Now, interval} {
+				if set {
+					modes++
+				}
+			}
+			needsTime := req.Day != "" || req.Date != ""
+			if modes != 1 || needsTime != (req.Time != "") {
+				sl.ReportError(req.Day, "Day", "Day", "day_time_or_now", "")
+				return
+			}
+			if req.Mode != "" && !interval {
+				sl.ReportError(req.Mode, "Mode", "Mode", "mode_without_interval", "")
+			}
+			if interval {
+				from, err1 := time.Parse("2006-01-02 15:04", req.From)
+				to, err2 := time.Parse("2006-01-02 15:04", req.To)
+				if err1 != nil || err2 != nil || to.Before(from) || to.Sub(from) > 7*24*time.Hour {
+					sl.ReportError(req.To, "To", "To", "interval_range", "")
+				}
+			}
+		}, api.OpenPharmaciesRequest{})
+
+		// Price tiers must not repeat a min quantity
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.MaskPriceTiersRequest)
+			seen := make(map[int]bool)
+			for _, tier := range req.Tiers {
+				if seen[tier.MinQuantity] {
+					sl.ReportError(req.Tiers, "Tiers", "Tiers", "unique_min_quantity", "")
+					return
+				}
+				seen[tier.MinQuantity] = true
+			}
+		}, api.MaskPriceTiersRequest{})
+
+		// Opening hours are given either as entries or as a raw string; an empty
+		// entries list clears the schedule
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.OpeningHoursRequest)
+			if (req.Raw != "") == (req.Entries != nil) {
+				sl.ReportError(req.Entries, "Entries", "Entries", "entries_or_raw", "")
+			}
+		}, api.OpeningHoursRequest{})
+
+		// Coordinates are set as a pair
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.CreatePharmacyRequest)
+			if (req.Latitude == nil) != (req.Longitude == nil) {
+				sl.ReportError(req.Latitude, "Latitude", "Latitude", "coordinates", "")
+			}
+		}, api.CreatePharmacyRequest{})
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.UpdatePharmacyRequest)
+			if (req.Latitude == nil) != (req.Longitude == nil) {
+				sl.ReportError(req.Latitude, "Latitude", "Latitude", "coordinates", "")
+			}
+		}, api.UpdatePharmacyRequest{})
+
+		// An override either closes the date or lists its shifts
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.OpeningOverrideRequest)
+			if req.Closed == (len(req.Shifts) > 0) {
+				sl.ReportError(req.Shifts, "Shifts", "Shifts", "closed_or_shifts", "")
+			}
+		}, api.OpeningOverrideRequest{})
+
+		// A price comparison is for either a product ID or a mask name
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.PriceComparisonRequest)
+			if (req.ProductID != 0) == (strings.TrimSpace(req.Name) != "") {
+				sl.ReportError(req.ProductID, "ProductID", "ProductID", "product_or_name", "")
+			}
+		}, api.PriceComparisonRequest{})
+
+		// Composite filter criteria come in complete, ordered pairs
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.CompositeFilterRequest)
+			if (req.Day != "") != (req.Time != "") {
+				sl.ReportError(req.Day, "Day", "Day", "day_and_time", "")
+			}
+			if (req.CountOperator != "") != (req.Count != nil) {
+				sl.ReportError(req.CountOperator, "CountOperator", "CountOperator", "operator_and_count", "")
+			}
+			between := req.CountOperator == "between"
+			if between != (req.CountMax != nil) || (between && req.Count != nil && *req.CountMax < *req.Count) {
+				sl.ReportError(req.CountMax, "CountMax", "CountMax", "count_range", "")
+			}
+			if req.MaxPrice > 0 && req.MinPrice > req.MaxPrice {
+				sl.ReportError(req.MaxPrice, "MaxPrice", "MaxPrice", "price_range", "")
+			}
+			if req.MinCashBalance != nil && req.MaxCashBalance != nil && *req.MinCashBalance > *req.MaxCashBalance {
+				sl.ReportError(req.MaxCashBalance, "MaxCashBalance", "MaxCashBalance", "cash_balance_range", "")
+			}
+		}, api.CompositeFilterRequest{})
+
+		// Discount value and date window must be consistent with the discount type
+		v.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(api.CreatePromotionRequest)
+			switch req.DiscountType {
+			case "percentage":
+				if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+					sl.ReportError(req.DiscountValue, "DiscountValue", "DiscountValue", "percentage_range", "")
+				}
+			case "fixed":
+				if req.DiscountValue <= 0 {
+					sl.ReportError(req.DiscountValue, "DiscountValue", "DiscountValue", "fixed_amount", "")
+				}
+			case "buy_x_get_y":
+				if req.BuyQuantity <= 0 {
+					sl.ReportError(req.BuyQuantity, "BuyQuantity", "BuyQuantity", "buy_quantity", "")
+				}
+				if req.FreeQuantity <= 0 {
+					sl.ReportError(req.FreeQuantity, "FreeQuantity", "FreeQuantity", "free_quantity", "")
+				}
+			}
+			if req.StartDate != "" && req.EndDate != "" {
+				startDate, err1 := time.Parse("2006-01-02", req.StartDate)
+				endDate, err2 := time.Parse("2006-01-02", req.EndDate)
+				if err1 == nil && err2 == nil && startDate.After(endDate) {
+					sl.ReportError(req.EndDate, "EndDate", "EndDate", "date_range", "")
+				}
+			}
+		}, api.CreatePromotionRequest{})
     }
 	return nil
 }
@@ -214,14 +435,13 @@ func FormatValidationError(err error, obj interface{}) map[string]string {
 		t := reflect.TypeOf(obj)
 
 		for _, fieldErr := range validationErrors {
-			fieldName := fieldErr.StructField()
-
-			if f, found := t.FieldByName(fieldName); found {
+			// Nested DTO fields are keyed by their path, e.g. "Tiers[0].MinQuantity"
+			if f, path, found := nestedField(t, fieldErr.StructNamespace(), fieldErr.Namespace()); found {
 				msg := f.Tag.Get("validate_msg")
 				if msg != "" {
-					errorsMap[fieldErr.Field()] = msg
+					errorsMap[path] = msg
 				} else {
-					errorsMap[fieldErr.Field()] = fieldErr.Error()
+					errorsMap[path] = fieldErr.Error()
 				}
 			}
 		}
@@ -230,14 +450,39 @@ func FormatValidationError(err error, obj interface{}) map[string]string {
 	return errorsMap
 }
 
-// Helper function to validate day of week using global Days slice
-func isValidDay(day string) (string, bool) {
-	for _, validDay := range global.Days {
-		if strings.EqualFold(day, validDay) {
-			return validDay, true // Return the properly cased day name
+// nestedField resolves a validator namespace such as "Request.Tiers[0].MinQuantity"
+// to the struct field it refers to, following slices, maps and pointers. It
+// also returns the field path below the request, in which embedded structs
+// (e.g. PageRequest) do not appear.
+func nestedField(t reflect.Type, structNamespace, namespace string) (reflect.StructField, string, bool) {
+	parts := strings.Split(structNamespace, ".")
+	names := strings.Split(namespace, ".")
+	var field reflect.StructField
+	if len(parts) < 2 || len(names) != len(parts) {
+		return field, "", false
+	}
+	var path []string
+	for i, part := range parts[1:] {
+		if j := strings.Index(part, "["); j >= 0 {
+			part = part[:j]
 		}
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return field, "", false
+		}
+		f, found := t.FieldByName(part)
+		if !found {
+			return field, "", false
+		}
+		if !f.Anonymous {
+			path = append(path, names[i+1])
+		}
+		field = f
+		t = f.Type
 	}
-	return "", false
+	return field, strings.Join(path, "."), true
 }
 
 // Helper function to check if a slice contains a string
@@ -248,4 +493,14 @@ func contains(slice []string, item string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// validList reports whether every item of a comma-separated list is a key of allowed
+func validList(list string, allowed map[string]string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if _, ok := allowed[strings.ToLower(strings.TrimSpace(item))]; !ok {
+			return false
+		}
+	}
+	return true
+}
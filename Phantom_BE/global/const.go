@@ -5,6 +5,8 @@ type ContextKey string
 const(
 	// VERSION used to identify artifact version
 	VERSION = "v1"
+	// RESOURCE_VERSION prefixes the RESTful GET resource routes
+	RESOURCE_VERSION = "v2"
 
 	// Middleware error handling keys
 	DBErrorKey          ContextKey = "db_error"
@@ -1,8 +1,14 @@
 package global
 
 import (
+	"math"
 	"os"
+	"strconv"
+	"sync"
 	"time"
+	_ "time/tzdata" // business time zone must load in minimal containers
+
+	"github.com/charmbracelet/log"
 )
 
 
@@ -14,6 +20,31 @@ type OpeningHour struct {
 	CloseTime string  `json:"close"` // "HH:MM"
 }
 
+// OpeningOverride replaces a pharmacy's weekly hours on one date, either
+// closed all day or open for Shifts instead
+type OpeningOverride struct {
+	ID         uint    `gorm:"primaryKey"`
+	PharmacyID uint    `gorm:"uniqueIndex:idx_opening_override_date" json:"pharmacyId"`
+	Date       string  `gorm:"uniqueIndex:idx_opening_override_date" json:"date"` // "YYYY-MM-DD"
+	Closed     bool    `json:"closed"`
+	Shifts     []Shift `gorm:"serializer:json" json:"shifts,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// Shift is one opening on an override date; it may run past midnight
+type Shift struct {
+	OpenTime  string `json:"open"`  // "HH:MM"
+	CloseTime string `json:"close"` // "HH:MM"
+}
+
+// Holiday is a date of the national holiday calendar. Pharmacies observing
+// holidays are closed on it unless they have an override for the date.
+type Holiday struct {
+	ID   uint   `gorm:"primaryKey"`
+	Date string `gorm:"uniqueIndex" json:"date"` // "YYYY-MM-DD"
+	Name string `json:"name"`
+}
+
 type RawPurchase struct {
 	PharmacyName      string `json:"pharmacyName"`
 	MaskName          string `json:"maskName"`
@@ -24,9 +55,19 @@ type RawPurchase struct {
 type Purchase struct {
 	ID                uint    `gorm:"primaryKey"`
 	UserID            uint
+	PharmacyID        uint    `gorm:"index" json:"pharmacyId,omitempty"` // zero for imported history
+	MaskID            uint    `gorm:"index" json:"maskId,omitempty"`      // zero for imported history
 	PharmacyName      string  `json:"pharmacyName"`
 	MaskName          string  `json:"maskName"`
 	TransactionAmount float64 `json:"transactionAmount"`
+	CommissionAmount  float64 `json:"commissionAmount"` // platform share of TransactionAmount
+	DiscountAmount    float64 `json:"discountAmount"`   // promotion discount already taken off TransactionAmount
+	PromotionCode     string  `json:"promotionCode,omitempty"`
+	PointsRedeemed    int     `json:"pointsRedeemed,omitempty"`
+	PointsEarned      int     `json:"pointsEarned,omitempty"`
+	LoyaltyEntryID    *uint   `json:"-"` // earn entry that credited PointsEarned
+	PromotionRedemptionID *uint `json:"-"` // coupon use that granted DiscountAmount
+	RefundedAt        *time.Time `json:"refundedAt,omitempty"`
 	TransactionDate   time.Time  `json:"transactionDate"`  
 }
 
@@ -40,14 +81,44 @@ type User struct {
 	ID                uint       `gorm:"primaryKey"`
 	Name              string     `json:"name"`
 	CashBalance       float64    `json:"cashBalance"`
+	LoyaltyPoints     int        `json:"loyaltyPoints"` // unexpired balance, mirrors the ledger
 	PurchaseHistories []Purchase `gorm:"foreignKey:UserID" json:"purchaseHistories"`
 }
 
+// Mask is one pharmacy's offer of a Product at its own price. Name and the
+// parsed attributes mirror the product.
 type Mask struct {
 	ID         uint    `gorm:"primaryKey"`
 	Name       string  `json:"name"`
 	Price      float64 `json:"price"`
 	PharmacyID uint
+	ProductID  uint    `gorm:"index" json:"productId"`
+	PriceTiers []MaskPriceTier `json:"priceTiers,omitempty" gorm:"foreignKey:MaskID"`
+	// Parsed from Name, e.g. "True Barrier (green) (3 per pack)"
+	Brand        string  `gorm:"index" json:"brand"`
+	Color        string  `gorm:"index" json:"color"`
+	PackSize     int     `json:"packSize"`
+	PricePerUnit float64 `json:"pricePerUnit"` // Price / PackSize
+	// Purchases not refunded, only read when a list is sorted by popularity
+	Sales int64 `gorm:"->;-:migration" json:"sales,omitempty"`
+}
+
+// Product is one distinct mask product, shared by the offers of every pharmacy
+type Product struct {
+	ID       uint   `gorm:"primaryKey"`
+	Name     string `gorm:"uniqueIndex" json:"name"`
+	Brand    string `gorm:"index" json:"brand"`
+	Color    string `gorm:"index" json:"color"`
+	PackSize int    `json:"packSize"`
+	Offers   []Mask `json:"offers,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+// MaskPriceTier discounts the unit price of a mask once a purchase reaches MinQuantity units
+type MaskPriceTier struct {
+	ID              uint    `gorm:"primaryKey"`
+	MaskID          uint    `gorm:"index" json:"maskId"`
+	MinQuantity     int     `json:"minQuantity"`
+	DiscountPercent float64 `json:"discountPercent"`
 }
 
 type PharmacyRaw struct {
@@ -55,6 +126,9 @@ type PharmacyRaw struct {
 	CashBalance     float64        `json:"cashBalance"`
 	OpeningHoursRaw string         `json:"openingHours"`
 	Masks           []Mask         `json:"masks"`
+	Address         string         `json:"address"`
+	Latitude        *float64       `json:"latitude"`
+	Longitude       *float64       `json:"longitude"`
 }
 
 type Pharmacy struct {
@@ -63,6 +137,102 @@ type Pharmacy struct {
 	CashBalance     float64        `json:"cashBalance"`
 	OpeningHours    []OpeningHour  `json:"openingHours" gorm:"foreignKey:PharmacyID"`
 	Masks           []Mask         `json:"masks" gorm:"foreignKey:PharmacyID"`
+	// Per-pharmacy commission overrides, nil falls back to the platform defaults
+	CommissionPercent  *float64    `json:"commissionPercent,omitempty"`
+	CommissionFixedFee *float64    `json:"commissionFixedFee,omitempty"`
+	// Accept online orders outside opening hours
+	AcceptsOrdersAnytime bool      `json:"acceptsOrdersAnytime"`
+	// Close on the dates of the national holiday calendar
+	ObservesHolidays     bool      `json:"observesHolidays"`
+	// Location, coordinates are nil when unknown
+	Address   string   `json:"address,omitempty"`
+	Latitude  *float64 `gorm:"index:idx_pharmacy_location" json:"latitude,omitempty"`
+	Longitude *float64 `gorm:"index:idx_pharmacy_location" json:"longitude,omitempty"`
+}
+
+// PlatformAccount collects the commission deducted from every purchase
+type PlatformAccount struct {
+	ID          uint    `gorm:"primaryKey"`
+	Name        string  `gorm:"uniqueIndex" json:"name"`
+	CashBalance float64 `json:"cashBalance"`
+}
+
+// Promotion is a coupon code together with the rules deciding who may redeem it
+type Promotion struct {
+	ID                uint       `gorm:"primaryKey"`
+	Code              string     `gorm:"uniqueIndex" json:"code"`
+	Description       string     `json:"description"`
+	DiscountType      string     `json:"discountType"`  // percentage, fixed or buy_x_get_y
+	DiscountValue     float64    `json:"discountValue"` // percent off or fixed amount off the order
+	BuyQuantity       int        `json:"buyQuantity,omitempty"`
+	FreeQuantity      int        `json:"freeQuantity,omitempty"`
+	UserID            *uint      `json:"userId,omitempty"`
+	PharmacyID        *uint      `json:"pharmacyId,omitempty"`
+	MaskNamePattern   string     `json:"maskNamePattern,omitempty"` // case-insensitive, "*" matches anything
+	FirstPurchaseOnly bool       `json:"firstPurchaseOnly"`
+	MinQuantity       int        `json:"minQuantity,omitempty"`
+	StartsAt          *time.Time `json:"startsAt,omitempty"`
+	EndsAt            *time.Time `json:"endsAt,omitempty"`
+	MaxUses           int        `json:"maxUses"`        // 0 means unlimited
+	MaxUsesPerUser    int        `json:"maxUsesPerUser"` // 0 means unlimited
+	UsedCount         int        `json:"usedCount"`
+	Active            bool       `json:"active"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+// PromotionRedemption records each purchase a promotion was applied to
+type PromotionRedemption struct {
+	ID             uint      `gorm:"primaryKey"`
+	PromotionID    uint      `gorm:"index" json:"promotionId"`
+	UserID         uint      `gorm:"index" json:"userId"`
+	PharmacyID     uint      `json:"pharmacyId"`
+	MaskID         uint      `json:"maskId"`
+	Quantity       int       `json:"quantity"`
+	DiscountAmount float64   `json:"discountAmount"`
+	RedeemedAt     time.Time `json:"redeemedAt"`
+}
+
+// LoyaltyPointEntry is one movement in a user's loyalty points ledger.
+// Earn and restore entries keep track of their unspent points so they can expire.
+type LoyaltyPointEntry struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index" json:"userId"`
+	Type      string     `json:"type"`   // earn, redeem, restore, expire or reversal
+	Points    int        `json:"points"` // positive for earn and restore, negative otherwise
+	Remaining int        `json:"remaining"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Subscription reorders the same mask from a pharmacy on a fixed schedule
+type Subscription struct {
+	ID            uint       `gorm:"primaryKey"`
+	UserID        uint       `gorm:"index" json:"userId"`
+	PharmacyID    uint       `json:"pharmacyId"`
+	MaskID        uint       `json:"maskId"`
+	Quantity      int        `json:"quantity"`
+	Frequency     string     `json:"frequency"` // daily, weekly or monthly
+	Every         int        `json:"every"`     // run every N days, weeks or months
+	NextRunAt     time.Time  `gorm:"index" json:"nextRunAt"`
+	StartsAt      *time.Time `json:"startsAt,omitempty"` // runs fall whole periods after it, unset before it was stored
+	Status        string     `json:"status"` // active, paused or cancelled
+	LastRunAt     *time.Time `json:"lastRunAt,omitempty"`
+	LastRunStatus string     `json:"lastRunStatus,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// SubscriptionRun is the outcome of one scheduled execution of a subscription
+type SubscriptionRun struct {
+	ID             uint      `gorm:"primaryKey"`
+	SubscriptionID uint      `gorm:"index" json:"subscriptionId"`
+	ScheduledAt    time.Time `json:"scheduledAt"`
+	RunAt          time.Time `json:"runAt"`
+	Status         string    `json:"status"` // success or failed
+	ErrorCode      string    `json:"errorCode,omitempty"`
+	ErrorMessage   string    `json:"errorMessage,omitempty"`
+	PurchaseIDs    []uint    `gorm:"serializer:json" json:"purchaseIds,omitempty"`
+	TotalAmount    float64   `json:"totalAmount"`
 }
 
 type DatabaseConfig struct {
@@ -109,11 +279,74 @@ var(
 		"Mon": "Monday", "Tue": "Tuesday", "Wed": "Wednesday",
 		"Thu": "Thursday", "Fri": "Friday", "Sat": "Saturday", "Sun": "Sunday",
 	}
+
+	// Shared secret of the admin endpoints, admin access is disabled when empty
+	AdminAPIToken = getEnv("ADMIN_API_TOKEN", "")
+
+	// Platform commission charged on each purchase, pharmacies may override both values
+	// within the same bounds
+	CommissionPercent   = getEnvFloatBetween("COMMISSION_PERCENT", 0, 0, 100)
+	CommissionFixedFee  = getEnvFloatBetween("COMMISSION_FIXED_FEE", 0, 0, math.Inf(1))
+	PlatformAccountName = getEnv("PLATFORM_ACCOUNT_NAME", "platform")
+
+	// Loyalty program: points earned per dollar spent, dollar value of a redeemed point and point lifetime
+	LoyaltyPointsPerDollar = getEnvFloat("LOYALTY_POINTS_PER_DOLLAR", 1)
+	LoyaltyPointValue      = getEnvFloat("LOYALTY_POINT_VALUE", 0.01)
+	LoyaltyPointsExpiryDays = int(getEnvFloat("LOYALTY_POINTS_EXPIRY_DAYS", 365))
+
+	// Time zone the pharmacies' opening hours are expressed in
+	BusinessTimeZone = getEnv("BUSINESS_TIME_ZONE", "Asia/Taipei")
+	// How often due subscriptions are executed
+	SubscriptionSchedulerInterval = getEnvSeconds("SUBSCRIPTION_SCHEDULER_INTERVAL_SECONDS", 60)
+	// How often the suggest index is reloaded besides after admin changes
+	SuggestRefreshInterval = getEnvSeconds("SUGGEST_REFRESH_INTERVAL_SECONDS", 300)
+
+	businessLocation     *time.Location
+	businessLocationOnce sync.Once
 )
 
+// BusinessLocation returns the business time zone, falling back to UTC when it cannot be loaded
+func BusinessLocation() *time.Location {
+	businessLocationOnce.Do(func() {
+		loc, err := time.LoadLocation(BusinessTimeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		businessLocation = loc
+	})
+	return businessLocation
+}
+
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
 	}
 	return fallback
-}
\ No newline at end of file
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// getEnvFloatBetween reads a float that must lie between low and high. Values
+// outside are logged and replaced by the fallback.
+func getEnvFloatBetween(key string, fallback, low, high float64) float64 {
+	value := getEnvFloat(key, fallback)
+	if math.IsNaN(value) || value < low || value > high {
+		log.Error("environment value out of range, using the default", "key", key, "value", value, "min", low, "max", high, "default", fallback)
+		return fallback
+	}
+	return value
+}
+
+// getEnvSeconds reads a duration in (possibly fractional) seconds. Tickers
+// cannot run on zero or negative intervals, so anything below one second
+// falls back to one second.
+func getEnvSeconds(key string, fallback float64) time.Duration {
+	return max(time.Duration(getEnvFloat(key, fallback)*float64(time.Second)), time.Second)
+}
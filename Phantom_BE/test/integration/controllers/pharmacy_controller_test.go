@@ -136,7 +136,7 @@ func TestGetOpenPharmacies(t *testing.T) {
 
         assert.Equal(t, http.StatusBadRequest, w.Code)
 
-        assert.Contains(t, w.Body.String(), "Time must be in HH:MM format")
+        assert.Contains(t, w.Body.String(), "Time must be a time of day")
 
     })
 